@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Allocation struct {
+	Nodes      []int
+	Ascendancy string
+}
+
+func ParseNodeList(s string) ([]int, error) {
+	nodes := make([]int, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		node, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node hash %q", part)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// returns the set of allocated node hashes, including the start node of the
+// chosen ascendancy which is always allocated in game
+func (a Allocation) NodeSet(tree Tree) map[int]bool {
+	set := make(map[int]bool, len(a.Nodes)+1)
+	for _, node := range a.Nodes {
+		set[node] = true
+	}
+	if a.Ascendancy != "" {
+		for _, node := range tree.Nodes {
+			if node.IsAscendancyStart && node.AscendancyName != nil && *node.AscendancyName == a.Ascendancy {
+				set[node.Skill] = true
+			}
+		}
+	}
+	return set
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+)
+
+type ClassOptions struct {
+	// directory the class and ascendancy artwork is referenced from, relative
+	// to the svg, only the outlines are drawn if empty
+	AssetDir string
+	// index into the tree's image zoom levels, negative for the largest
+	ZoomLevel int
+}
+
+// finds an ascendancy of any class or the alternate ascendancies by id or name
+func (t Tree) FindAscendancy(name string) (Ascendancy, int, bool) {
+	for i, class := range t.Classes {
+		for _, ascendancy := range class.Ascendancies {
+			if ascendancy.Id == name || ascendancy.Name == name {
+				return ascendancy, i, true
+			}
+		}
+	}
+	for _, ascendancy := range t.AlternateAscendancies {
+		if ascendancy.Id == name || ascendancy.Name == name {
+			return ascendancy, -1, true
+		}
+	}
+	return Ascendancy{}, -1, false
+}
+
+// the class start is allocated when the chosen ascendancy belongs to the
+// class or a neighbouring node is allocated
+func (d *TreeDrawer) isClassAllocated(node Node, classIndex int) bool {
+	if d.Options.Allocation == nil {
+		return false
+	}
+	if _, ascendancyClass, exists := d.Tree.FindAscendancy(d.Options.Allocation.Ascendancy); exists && ascendancyClass == classIndex {
+		return true
+	}
+	for _, neighbourId := range append(append([]string{}, node.Out...), node.In...) {
+		if d.IsAllocated(d.Tree.Nodes[neighbourId]) {
+			return true
+		}
+	}
+	return false
+}
+
+// draws a sprite centered on a point, scaled from the zoom level to the tree,
+// and returns its top left corner
+func (d *TreeDrawer) drawArtwork(spriteType string, image string, x int, y int, id string) (int, int, bool) {
+	options := d.Options.Classes
+	if options.AssetDir == "" {
+		return 0, 0, false
+	}
+	sprite, exists := d.Tree.SpriteSheet(spriteType, options.ZoomLevel)
+	if !exists {
+		return 0, 0, false
+	}
+	coords, exists := sprite.Coords[image]
+	if !exists {
+		return 0, 0, false
+	}
+	zoom := d.Tree.ImageZoom(options.ZoomLevel)
+	w := int(float64(coords.W) / zoom)
+	h := int(float64(coords.H) / zoom)
+	d.s.Def()
+	d.SpritePattern(id, sprite, coords, options.AssetDir, "none")
+	d.s.DefEnd()
+	d.s.Rect(x-w/2, y-h/2, w, h, fmt.Sprintf("style=\"fill:url(#%s)\"", id))
+	return x - w/2, y - h/2, true
+}
+
+// draws the class start portraits and the frames of the ascendancy trees
+func (d *TreeDrawer) DrawClasses() {
+	d.s.Gid("classes")
+	for _, nodeid := range SortedNodeIds(d.Tree) {
+		node := d.Tree.Nodes[nodeid]
+		if node.ClassStartIndex != nil {
+			d.DrawClassStart(node)
+		}
+	}
+	for _, nodeid := range SortedNodeIds(d.Tree) {
+		node := d.Tree.Nodes[nodeid]
+		if node.IsAscendancyStart && node.AscendancyName != nil {
+			d.DrawAscendancyFrame(node)
+		}
+	}
+	d.s.Gend()
+}
+
+func (d *TreeDrawer) DrawClassStart(node Node) {
+	classIndex := *node.ClassStartIndex
+	if classIndex < 0 || classIndex >= len(d.Tree.Classes) {
+		return
+	}
+	x, y, err := d.GetCoordinates(node)
+	if err != nil {
+		return
+	}
+	class := d.Tree.Classes[classIndex]
+	classes := []string{"class-start"}
+	if d.isClassAllocated(node, classIndex) {
+		classes = append(classes, "allocated")
+	}
+	d.drawArtwork("startNode", "center"+strings.ToLower(class.Name), x, y, fmt.Sprintf("class-art-%d", classIndex))
+	radius := d.Tree.Constants.PSSCentreInnerRadius
+	d.s.Circle(x, y, radius, fmt.Sprintf("id=\"class-%d\" class=\"%s\" data-extras=\"%s\"", classIndex, strings.Join(classes, " "), html.EscapeString(class.Name)))
+	d.s.Text(x, y, class.Name, "class=\"class-name\" text-anchor=\"middle\" dominant-baseline=\"middle\"")
+}
+
+// returns the radius around the start node enclosing all nodes of an
+// ascendancy
+func (d *TreeDrawer) ascendancyRadius(start Node, x int, y int) int {
+	radius := 0.0
+	for _, node := range d.Tree.Nodes {
+		if node.AscendancyName == nil || *node.AscendancyName != *start.AscendancyName || node.IsBloodline != start.IsBloodline {
+			continue
+		}
+		nx, ny, err := d.GetCoordinates(node)
+		if err != nil {
+			continue
+		}
+		radius = max(radius, math.Hypot(float64(nx-x), float64(ny-y)))
+	}
+	return int(radius) + 150
+}
+
+func (d *TreeDrawer) DrawAscendancyFrame(start Node) {
+	x, y, err := d.GetCoordinates(start)
+	if err != nil {
+		return
+	}
+	name := *start.AscendancyName
+	ascendancy, _, exists := d.Tree.FindAscendancy(name)
+	if !exists {
+		ascendancy = Ascendancy{Id: name, Name: name}
+	}
+	radius := d.ascendancyRadius(start, x, y)
+	extras := html.EscapeString(name)
+	left, top, drawn := d.drawArtwork("ascendancyBackground", "Classes"+ascendancy.Id, x, y, fmt.Sprintf("ascendancy-art-%d", start.Skill))
+	if !drawn {
+		left, top = x-radius, y-radius
+	}
+	d.s.Circle(x, y, radius, fmt.Sprintf("id=\"a-%d\" class=\"ascendancy ascendancy-frame\" data-extras=\"%s\"", start.Skill, extras))
+	d.s.Text(x, y-radius+80, ascendancy.Name, fmt.Sprintf("class=\"ascendancy ascendancy-name\" data-extras=\"%s\" text-anchor=\"middle\"", extras))
+
+	// the flavour text rect is relative to the top left corner of the artwork,
+	// or of the frame without artwork
+	if ascendancy.FlavourText == nil || ascendancy.FlavourTextRect == nil {
+		return
+	}
+	rect := ascendancy.FlavourTextRect
+	attr := fmt.Sprintf("class=\"ascendancy flavour-text\" data-extras=\"%s\"", extras)
+	if ascendancy.FlavourTextColour != nil {
+		attr += fmt.Sprintf(" style=\"fill:#%s\"", html.EscapeString(strings.TrimPrefix(*ascendancy.FlavourTextColour, "#")))
+	}
+	lines := strings.Split(*ascendancy.FlavourText, "\n")
+	lineHeight := rect.Height / max(len(lines), 1)
+	for i, line := range lines {
+		d.s.Text(left+rect.X, top+rect.Y+lineHeight*i+lineHeight/2, strings.TrimSpace(line), attr, "dominant-baseline=\"middle\"")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDrawClassStart(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := renderSvg(t, tree, DrawOptions{Classes: &ClassOptions{}})
+	// the circle has the inner radius of the start node artwork
+	start := `<circle cx="0" cy="0" r="130" id="class-1" class="class-start" data-extras="Witch" />`
+	if !strings.Contains(svg, start) {
+		t.Errorf("missing %s in\n%s", start, svg)
+	}
+	if !strings.Contains(svg, `class="class-name" text-anchor="middle" dominant-baseline="middle" >Witch</text>`) {
+		t.Error("the class name is missing")
+	}
+	if element := svgElement(svg, "a-200"); !slices.Contains(svgClasses(element), "ascendancy-frame") {
+		t.Errorf("the occultist frame is missing: %q", element)
+	}
+
+	tests := []struct {
+		name       string
+		allocation Allocation
+		allocated  bool
+	}{
+		{"neighbour", Allocation{Nodes: []int{101}}, true},
+		{"ascendancy", Allocation{Ascendancy: "Occultist"}, true},
+		{"other class", Allocation{Ascendancy: "Ascendant"}, false},
+		{"distant node", Allocation{Nodes: []int{103}}, false},
+	}
+	for _, test := range tests {
+		svg := renderSvg(t, tree, DrawOptions{Classes: &ClassOptions{}, Allocation: &test.allocation})
+		classes := svgClasses(svgElement(svg, "class-1"))
+		if allocated := slices.Contains(classes, "allocated"); allocated != test.allocated {
+			t.Errorf("%s: class start classes %v, want allocated %v", test.name, classes, test.allocated)
+		}
+	}
+}
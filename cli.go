@@ -0,0 +1,479 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type TreeKind struct {
+	Name     string
+	InputDir string
+	Title    string
+	// github repository the exports are published in, empty if there is none
+	Repo string
+}
+
+var TreeKinds = []TreeKind{
+	{Name: "atlas", InputDir: "atlastree", Title: "Atlas tree", Repo: "grindinggear/atlastree-export"},
+	{Name: "passives", InputDir: "skilltree", Title: "Passive tree", Repo: "grindinggear/skilltree-export"},
+	{Name: "poe2", InputDir: "poe2tree", Title: "Path of Exile 2 passive tree"},
+}
+
+func GetTreeKind(name string) (TreeKind, error) {
+	for _, kind := range TreeKinds {
+		if kind.Name == name {
+			return kind, nil
+		}
+	}
+	return TreeKind{}, fmt.Errorf("unknown tree kind %q", name)
+}
+
+type TreeFile struct {
+	Kind    TreeKind
+	Version string
+	Path    string
+}
+
+// resolves the export of a version, which may be saved as .json, .txt or
+// .data depending on when it was published
+func NewTreeFile(inputDir string, kind TreeKind, version string) TreeFile {
+	file := TreeFile{
+		Kind:    kind,
+		Version: version,
+		Path:    filepath.Join(inputDir, kind.InputDir, version+".json"),
+	}
+	for _, dataFile := range exportDataFiles {
+		path := filepath.Join(inputDir, kind.InputDir, version+dataFile.Extension)
+		if _, err := os.Stat(path); err == nil {
+			file.Path = path
+			break
+		}
+	}
+	return file
+}
+
+func exportVersion(fileName string) (string, bool) {
+	for _, dataFile := range exportDataFiles {
+		if strings.HasSuffix(fileName, dataFile.Extension) {
+			return strings.TrimSuffix(fileName, dataFile.Extension), true
+		}
+	}
+	return "", false
+}
+
+type Config struct {
+	InputDir  string
+	OutputDir string
+	Kinds     []TreeKind
+	Versions  []string
+}
+
+func (c Config) SvgPath(file TreeFile) string {
+	return filepath.Join(c.OutputDir, "svg", file.Kind.Name, file.Version+".svg")
+}
+
+func (c Config) JsonPath(file TreeFile) string {
+	return filepath.Join(c.OutputDir, "json", file.Kind.Name, file.Version+".json")
+}
+
+func (c Config) MatchesVersion(version string) bool {
+	if len(c.Versions) == 0 {
+		return true
+	}
+	for _, v := range c.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// registers the flags shared by all commands that operate on tree files
+func NewFlagSet(name string) (*flag.FlagSet, func() (Config, error)) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	in := fs.String("in", ".", "directory containing the atlastree/, skilltree/ and poe2tree/ exports")
+	out := fs.String("out", ".", "directory to write the svg/, json/, html/, png/, tiles/ and pdf/ output to")
+	kind := fs.String("kind", "all", "tree kind to process: atlas, passives, poe2 or all")
+	versions := fs.String("version", "", "comma separated list of versions to process, e.g. 3.25,3.26 (default all)")
+	return fs, func() (Config, error) {
+		kinds, err := ParseTreeKinds(*kind)
+		if err != nil {
+			return Config{}, err
+		}
+		return Config{
+			InputDir:  *in,
+			OutputDir: *out,
+			Kinds:     kinds,
+			Versions:  ParseVersions(*versions),
+		}, nil
+	}
+}
+
+func ParseTreeKinds(s string) ([]TreeKind, error) {
+	if s == "all" {
+		return TreeKinds, nil
+	}
+	kinds := make([]TreeKind, 0)
+	for _, name := range strings.Split(s, ",") {
+		kind, err := GetTreeKind(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+func ParseVersions(s string) []string {
+	versions := make([]string, 0)
+	for _, version := range strings.Split(s, ",") {
+		version = strings.TrimSpace(version)
+		if version != "" {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+func ParseConfig(name string, args []string) (Config, error) {
+	fs, config := NewFlagSet(name)
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return Config{}, err
+	}
+	return config()
+}
+
+func CheckNoArgs(fs *flag.FlagSet) error {
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// registers the flags controlling how trees are drawn
+func AddDrawFlags(fs *flag.FlagSet) func() (DrawOptions, error) {
+	nodes := fs.String("nodes", "", "comma separated list of allocated node hashes to highlight")
+	ascendancy := fs.String("ascendancy", "", "name of the allocated ascendancy to highlight")
+	icons := fs.String("icons", "", "fill the nodes with their icons, loading the sprite sheets from this directory relative to the SVG")
+	backgrounds := fs.String("backgrounds", "", "draw the group backgrounds, loading the sprite sheets from this directory relative to the SVG")
+	orbits := fs.Bool("orbits", false, "draw the orbit rings of the groups")
+	classes := fs.Bool("classes", false, "draw the class starts and ascendancy frames, with artwork from the -backgrounds directory")
+	labels := fs.Bool("labels", false, "write the names of keystones and notables next to them")
+	labelSize := fs.Int("label-size", 40, "font size of the labels")
+	metadata := fs.String("metadata", "", "add titles and these data attributes to the nodes: all or a comma separated list of "+strings.Join(MetadataFields, ", "))
+	describe := fs.Bool("describe", false, "add a title and metadata naming the tree version, source file hash and generator")
+	legend := fs.Bool("legend", false, "draw a legend of the node classes in the bottom left corner")
+	theme := fs.String("theme", "", "style the SVG with a built-in theme, "+strings.Join(ThemeNames(), ", ")+", or a CSS file")
+	themeUrl := fs.String("theme-url", "", "import the stylesheet from this URL instead of embedding the theme")
+	spriteZoom := fs.Int("sprite-zoom", -1, "index of the sprite zoom level to use for icons and backgrounds (default the largest)")
+	return func() (DrawOptions, error) {
+		options := DrawOptions{StyleLink: *themeUrl}
+		// it would end the cdata section the stylesheet import is written in
+		if strings.Contains(*themeUrl, "]]>") {
+			return options, fmt.Errorf("invalid theme url %q: must not contain \"]]>\"", *themeUrl)
+		}
+		if *theme != "" {
+			style, err := LoadTheme(*theme)
+			if err != nil {
+				return options, err
+			}
+			options.Style = style
+		}
+		if *icons != "" {
+			options.Icons = &IconOptions{AssetDir: *icons, ZoomLevel: *spriteZoom}
+		}
+		if *backgrounds != "" || *orbits {
+			options.Groups = &GroupOptions{AssetDir: *backgrounds, ZoomLevel: *spriteZoom, Orbits: *orbits}
+		}
+		if *classes {
+			options.Classes = &ClassOptions{AssetDir: *backgrounds, ZoomLevel: *spriteZoom}
+		}
+		if *metadata != "" {
+			fields, err := ParseMetadataFields(*metadata)
+			if err != nil {
+				return options, err
+			}
+			options.Metadata = &MetadataOptions{Fields: fields}
+		}
+		if *describe || *legend {
+			options.Document = &DocumentOptions{Describe: *describe, Legend: *legend}
+		}
+		if *labels {
+			if *labelSize <= 0 {
+				return options, fmt.Errorf("invalid label size %d", *labelSize)
+			}
+			options.Labels = &LabelOptions{FontSize: *labelSize}
+		}
+		if *nodes != "" || *ascendancy != "" {
+			allocated, err := ParseNodeList(*nodes)
+			if err != nil {
+				return options, err
+			}
+			options.Allocation = &Allocation{Nodes: allocated, Ascendancy: *ascendancy}
+		}
+		return options, nil
+	}
+}
+
+func FindTreeFiles(cfg Config) ([]TreeFile, error) {
+	files := make([]TreeFile, 0)
+	for _, kind := range cfg.Kinds {
+		dir := filepath.Join(cfg.InputDir, kind.InputDir)
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) && len(cfg.Kinds) > 1 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			version, ok := exportVersion(entry.Name())
+			if !ok || seen[version] || !cfg.MatchesVersion(version) {
+				continue
+			}
+			seen[version] = true
+			files = append(files, NewTreeFile(cfg.InputDir, kind, version))
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Kind.Name != files[j].Kind.Name {
+			return files[i].Kind.Name < files[j].Kind.Name
+		}
+		return CompareVersions(files[i].Version, files[j].Version) < 0
+	})
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no tree files found in %s", cfg.InputDir)
+	}
+	return files, nil
+}
+
+// picks the tree for commands that operate on a single version, defaulting to
+// the passive tree and its latest version
+func SelectTreeFile(cfg Config) (TreeFile, error) {
+	if len(cfg.Kinds) > 1 {
+		kind, err := GetTreeKind("passives")
+		if err != nil {
+			return TreeFile{}, err
+		}
+		cfg.Kinds = []TreeKind{kind}
+	}
+	if len(cfg.Versions) > 1 {
+		return TreeFile{}, fmt.Errorf("expected a single version, got %s", strings.Join(cfg.Versions, ","))
+	}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return TreeFile{}, err
+	}
+	return files[len(files)-1], nil
+}
+
+// compares dotted version strings numerically, so that 3.9 sorts before 3.10
+func CompareVersions(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		intA, errA := strconv.Atoi(partsA[i])
+		intB, errB := strconv.Atoi(partsB[i])
+		if errA != nil || errB != nil {
+			if c := strings.Compare(partsA[i], partsB[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		if intA != intB {
+			return intA - intB
+		}
+	}
+	return len(partsA) - len(partsB)
+}
+
+func MakeOutputDirs(cfg Config, formats ...string) error {
+	for _, format := range formats {
+		for _, kind := range cfg.Kinds {
+			err := os.MkdirAll(filepath.Join(cfg.OutputDir, format, kind.Name), os.ModePerm)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func RunRender(args []string) error {
+	fs, config := NewFlagSet("render")
+	drawOptions := AddDrawFlags(fs)
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	options, err := drawOptions()
+	if err != nil {
+		return err
+	}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return err
+	}
+	err = MakeOutputDirs(cfg, "svg")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Generating SVG for %s %s\n", file.Kind.Name, file.Version)
+		fileOptions, err := options.ForFile(file)
+		if err != nil {
+			return err
+		}
+		DrawTree(file.Path, cfg.SvgPath(file), fileOptions)
+	}
+	return nil
+}
+
+func RunCompact(args []string) error {
+	cfg, err := ParseConfig("compact", args)
+	if err != nil {
+		return err
+	}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return err
+	}
+	err = MakeOutputDirs(cfg, "json")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Generating compact JSON for %s %s\n", file.Kind.Name, file.Version)
+		SaveCompactJson(file.Path, cfg.JsonPath(file))
+	}
+	return nil
+}
+
+func RunAll(args []string) error {
+	fs, config := NewFlagSet("all")
+	drawOptions := AddDrawFlags(fs)
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	options, err := drawOptions()
+	if err != nil {
+		return err
+	}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return err
+	}
+	err = MakeOutputDirs(cfg, "svg", "json")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Generating SVG and compact JSON for %s %s\n", file.Kind.Name, file.Version)
+		fileOptions, err := options.ForFile(file)
+		if err != nil {
+			return err
+		}
+		DrawTree(file.Path, cfg.SvgPath(file), fileOptions)
+		SaveCompactJson(file.Path, cfg.JsonPath(file))
+	}
+	return nil
+}
+
+func PrintUsage() {
+	fmt.Fprint(os.Stderr, `Usage: treegen <command> [flags]
+
+Commands:
+  render    generate SVG files from the tree exports
+  compact   generate compact JSON files from the tree exports
+  all       generate both SVG and compact JSON files (default)
+  html      generate self-contained HTML viewers for offline use
+  png       generate PNG images of the trees without external tools
+  tiles     generate zoom level PNG tile pyramids for slippy map viewers
+  pdf       generate printable PDF posters, optionally split across pages
+  themes    write the built-in themes as CSS files to customize or link
+  serve     serve SVG and compact JSON files rendered on demand over HTTP
+  url       decode or encode official passive tree share urls
+  path      show the shortest path and point cost to reach nodes
+  plan      plan a small connected allocation reaching all given nodes
+  validate  check that an allocation is possible on a tree version
+  diff      report node changes between two tree versions
+  heatmap   render how often nodes are allocated across many builds
+  fetch     download the tree exports for all released versions
+  sprites   slice the sprite sheets into one png per node icon
+  help      show this message
+
+Flags:
+  -in string       directory containing the atlastree/, skilltree/ and poe2tree/ exports (default ".")
+  -out string      directory to write the svg/, json/, html/, png/, tiles/ and pdf/ output to (default ".")
+  -kind string     tree kind to process: atlas, passives, poe2 or all (default "all")
+  -version string  comma separated list of versions to process, e.g. 3.25,3.26
+
+Render flags (render, all, html, png, tiles, pdf):
+  -nodes string       comma separated list of allocated node hashes to highlight
+  -ascendancy string  name of the allocated ascendancy to highlight
+  -icons string       fill the nodes with their icons, loading the sprite sheets
+                      from this directory relative to the SVG
+  -backgrounds string draw the group backgrounds, loading the sprite sheets
+                      from this directory relative to the SVG
+  -orbits             draw the orbit rings of the groups
+  -classes            draw the class starts and ascendancy frames, with artwork
+                      from the -backgrounds directory
+  -labels             write the names of keystones and notables next to them
+  -label-size int     font size of the labels (default 40)
+  -metadata string    add titles and these data attributes to the nodes: all or a
+                      comma separated list of name, stats, type, ascendancy,
+                      group, orbit
+  -sprite-zoom int    index of the sprite zoom level to use for icons and
+                      backgrounds (default the largest)
+  -describe           add a title and metadata naming the tree version, source
+                      file hash and generator (svg and html only)
+  -legend             draw a legend of the node classes (svg and html only)
+  -theme string       style with a built-in theme: dark, light, print,
+                      high-contrast or colorblind, or a CSS file (default no
+                      style for render and all, dark otherwise)
+  -theme-url string   import the stylesheet from this URL instead of embedding
+                      the theme (svg and html only)
+
+PNG flags (png, tiles):
+  -width int          width of the images in pixels (default 2048, png only)
+  -tile-size int      width and height of the tiles in pixels (default 256,
+                      tiles only)
+  -background string  background color of the images (default the background
+                      of the theme)
+
+PDF flags:
+  -paper string       paper size: a0 to a4, letter, legal, tabloid, arch-c,
+                      arch-d or arch-e (default "a1")
+  -orientation string auto, portrait or landscape (default "auto")
+  -pages string       split the poster across columns x rows pages (default "1x1")
+  -background string  background color of the poster (default the background
+                      of the theme)
+  The ascendancy trees overlap, a poster only prints the -ascendancy one and
+  leaves ascendancies out entirely, legend included, without it.
+
+Run "treegen <command> -h" for the flags of a single command.
+`)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"3.9", "3.10", -1},
+		{"3.10", "3.9", 1},
+		{"3.25", "3.25", 0},
+		{"3.25", "3.25.1", -1},
+		{"0.1", "3.0", -1},
+		{"3.25-atlas", "3.25-atlas", 0},
+	}
+	for _, test := range tests {
+		got := CompareVersions(test.a, test.b)
+		if (got < 0) != (test.want < 0) || (got > 0) != (test.want > 0) {
+			t.Errorf("CompareVersions(%q, %q) = %d, want sign %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestParseVersions(t *testing.T) {
+	got := ParseVersions(" 3.25, ,3.26,")
+	if !slices.Equal(got, []string{"3.25", "3.26"}) {
+		t.Errorf("ParseVersions = %v", got)
+	}
+	if got := ParseVersions(""); len(got) != 0 {
+		t.Errorf("ParseVersions(\"\") = %v, want none", got)
+	}
+}
+
+func TestParseTreeKinds(t *testing.T) {
+	kinds, err := ParseTreeKinds("all")
+	if err != nil || len(kinds) != len(TreeKinds) {
+		t.Errorf("ParseTreeKinds(all) = %v, %v", kinds, err)
+	}
+	kinds, err = ParseTreeKinds("passives, atlas")
+	if err != nil || len(kinds) != 2 || kinds[0].Name != "passives" || kinds[1].Name != "atlas" {
+		t.Errorf("ParseTreeKinds(passives, atlas) = %v, %v", kinds, err)
+	}
+	_, err = ParseTreeKinds("passives,unknown")
+	if err == nil {
+		t.Error("ParseTreeKinds accepted an unknown kind")
+	}
+}
+
+func TestMatchesVersion(t *testing.T) {
+	if !(Config{}).MatchesVersion("3.25") {
+		t.Error("an empty version filter should match every version")
+	}
+	cfg := Config{Versions: []string{"3.25", "3.26"}}
+	if !cfg.MatchesVersion("3.26") || cfg.MatchesVersion("3.2") {
+		t.Error("the version filter should only match the listed versions")
+	}
+}
+
+func TestFindTreeFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"skilltree/3.9.txt", "skilltree/3.10.json", "skilltree/3.10.txt", "skilltree/2.6.data", "skilltree/notes.md", "atlastree/3.25.json"} {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte("{}"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	kinds, _ := ParseTreeKinds("all")
+	files, err := FindTreeFiles(Config{InputDir: dir, Kinds: kinds})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(files))
+	for i, file := range files {
+		got[i] = file.Kind.Name + " " + filepath.Base(file.Path)
+	}
+	// versions are sorted numerically and .json is preferred over .txt
+	want := []string{"atlas 3.25.json", "passives 2.6.data", "passives 3.9.txt", "passives 3.10.json"}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindTreeFiles = %v, want %v", got, want)
+	}
+
+	files, err = FindTreeFiles(Config{InputDir: dir, Kinds: kinds, Versions: []string{"3.9"}})
+	if err != nil || len(files) != 1 || files[0].Version != "3.9" {
+		t.Errorf("FindTreeFiles with a version filter = %v, %v", files, err)
+	}
+	_, err = FindTreeFiles(Config{InputDir: dir, Kinds: kinds, Versions: []string{"1.0"}})
+	if err == nil {
+		t.Error("FindTreeFiles should fail when no file matches")
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type NodePosition struct {
+	Group      int `json:"group"`
+	Orbit      int `json:"orbit"`
+	OrbitIndex int `json:"orbitIndex"`
+}
+
+type DiffNode struct {
+	Hash  int      `json:"hash"`
+	Name  string   `json:"name"`
+	Stats []string `json:"stats,omitempty"`
+}
+
+type NodeChange struct {
+	Hash                  int           `json:"hash"`
+	Name                  string        `json:"name"`
+	OldName               string        `json:"oldName,omitempty"`
+	RemovedStats          []string      `json:"removedStats,omitempty"`
+	AddedStats            []string      `json:"addedStats,omitempty"`
+	FlagChanges           []string      `json:"flagChanges,omitempty"`
+	MasteryEffectsChanged bool          `json:"masteryEffectsChanged,omitempty"`
+	OldPosition           *NodePosition `json:"oldPosition,omitempty"`
+	NewPosition           *NodePosition `json:"newPosition,omitempty"`
+}
+
+type TreeDiff struct {
+	Kind       string       `json:"kind"`
+	OldVersion string       `json:"oldVersion"`
+	NewVersion string       `json:"newVersion"`
+	Added      []DiffNode   `json:"added"`
+	Removed    []DiffNode   `json:"removed"`
+	Renamed    []NodeChange `json:"renamed"`
+	Changed    []NodeChange `json:"changed"`
+	Moved      []NodeChange `json:"moved"`
+}
+
+func nodeName(node Node, hash int) string {
+	if node.Name == nil {
+		return strconv.Itoa(hash)
+	}
+	return *node.Name
+}
+
+func nodeFlags(node Node) map[string]bool {
+	return map[string]bool{
+		"isNotable":     node.IsNotable,
+		"isKeystone":    node.IsKeystone,
+		"isMastery":     node.IsMastery,
+		"isJewelSocket": node.IsJewelSocket,
+		"isWormhole":    node.IsWormhole,
+		"isBloodline":   node.IsBloodline,
+		"isBlighted":    node.IsBlighted,
+		"isProxy":       node.IsProxy,
+	}
+}
+
+func nodePosition(node Node) NodePosition {
+	return NodePosition{Group: node.Group, Orbit: node.Orbit, OrbitIndex: node.OrbitIndex}
+}
+
+// returns the entries of a missing in b and the entries of b missing in a
+func diffStrings(a, b []string) ([]string, []string) {
+	removed := make([]string, 0)
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			removed = append(removed, item)
+		}
+	}
+	added := make([]string, 0)
+	for _, item := range b {
+		if !slices.Contains(a, item) {
+			added = append(added, item)
+		}
+	}
+	return removed, added
+}
+
+func masteryEffectsEqual(a, b []MasteryEffect) bool {
+	return slices.EqualFunc(a, b, func(x, y MasteryEffect) bool {
+		return x.Effect == y.Effect && slices.Equal(x.Stats, y.Stats)
+	})
+}
+
+func numericNodes(tree Tree) map[int]Node {
+	nodes := make(map[int]Node, len(tree.Nodes))
+	for nodeid, node := range tree.Nodes {
+		hash, err := strconv.Atoi(nodeid)
+		if err == nil {
+			nodes[hash] = node
+		}
+	}
+	return nodes
+}
+
+func DiffTrees(oldTree Tree, newTree Tree) TreeDiff {
+	diff := TreeDiff{
+		Added:   make([]DiffNode, 0),
+		Removed: make([]DiffNode, 0),
+		Renamed: make([]NodeChange, 0),
+		Changed: make([]NodeChange, 0),
+		Moved:   make([]NodeChange, 0),
+	}
+	oldNodes := numericNodes(oldTree)
+	newNodes := numericNodes(newTree)
+
+	for hash, node := range newNodes {
+		if _, exists := oldNodes[hash]; !exists {
+			diff.Added = append(diff.Added, DiffNode{Hash: hash, Name: nodeName(node, hash), Stats: node.Stats})
+		}
+	}
+	for hash, oldNode := range oldNodes {
+		newNode, exists := newNodes[hash]
+		if !exists {
+			diff.Removed = append(diff.Removed, DiffNode{Hash: hash, Name: nodeName(oldNode, hash), Stats: oldNode.Stats})
+			continue
+		}
+		name := nodeName(newNode, hash)
+		oldName := nodeName(oldNode, hash)
+		if name != oldName {
+			diff.Renamed = append(diff.Renamed, NodeChange{Hash: hash, Name: name, OldName: oldName})
+		}
+
+		change := NodeChange{Hash: hash, Name: name}
+		change.RemovedStats, change.AddedStats = diffStrings(oldNode.Stats, newNode.Stats)
+		oldFlags := nodeFlags(oldNode)
+		for flag, value := range nodeFlags(newNode) {
+			if oldFlags[flag] != value {
+				change.FlagChanges = append(change.FlagChanges, fmt.Sprintf("%s: %t -> %t", flag, oldFlags[flag], value))
+			}
+		}
+		sort.Strings(change.FlagChanges)
+		change.MasteryEffectsChanged = !masteryEffectsEqual(oldNode.MasteryEffects, newNode.MasteryEffects)
+		if len(change.RemovedStats) > 0 || len(change.AddedStats) > 0 || len(change.FlagChanges) > 0 || change.MasteryEffectsChanged {
+			diff.Changed = append(diff.Changed, change)
+		}
+
+		oldPosition, newPosition := nodePosition(oldNode), nodePosition(newNode)
+		if oldPosition != newPosition {
+			diff.Moved = append(diff.Moved, NodeChange{Hash: hash, Name: name, OldPosition: &oldPosition, NewPosition: &newPosition})
+		}
+	}
+
+	sortDiffNodes(diff.Added)
+	sortDiffNodes(diff.Removed)
+	sortNodeChanges(diff.Renamed)
+	sortNodeChanges(diff.Changed)
+	sortNodeChanges(diff.Moved)
+	return diff
+}
+
+func sortDiffNodes(nodes []DiffNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Name != nodes[j].Name {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].Hash < nodes[j].Hash
+	})
+}
+
+func sortNodeChanges(changes []NodeChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Hash < changes[j].Hash
+	})
+}
+
+func (d TreeDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Renamed) == 0 && len(d.Changed) == 0 && len(d.Moved) == 0
+}
+
+func (d TreeDiff) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+func (d TreeDiff) WriteMarkdown(w io.Writer) error {
+	title := "Tree"
+	if kind, err := GetTreeKind(d.Kind); err == nil {
+		title = kind.Title
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s changes from %s to %s\n", title, d.OldVersion, d.NewVersion)
+	if d.IsEmpty() {
+		b.WriteString("\nNo changes.\n")
+	}
+	writeNodes := func(title string, nodes []DiffNode) {
+		if len(nodes) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", title, len(nodes))
+		for _, node := range nodes {
+			fmt.Fprintf(&b, "- **%s** (%d)", node.Name, node.Hash)
+			if len(node.Stats) > 0 {
+				fmt.Fprintf(&b, ": %s", strings.Join(node.Stats, "; "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	writeNodes("Added nodes", d.Added)
+	writeNodes("Removed nodes", d.Removed)
+
+	if len(d.Renamed) > 0 {
+		fmt.Fprintf(&b, "\n## Renamed nodes (%d)\n\n", len(d.Renamed))
+		for _, change := range d.Renamed {
+			fmt.Fprintf(&b, "- %s → **%s** (%d)\n", change.OldName, change.Name, change.Hash)
+		}
+	}
+	if len(d.Changed) > 0 {
+		fmt.Fprintf(&b, "\n## Changed nodes (%d)\n\n", len(d.Changed))
+		for _, change := range d.Changed {
+			fmt.Fprintf(&b, "- **%s** (%d)\n", change.Name, change.Hash)
+			for _, stat := range change.RemovedStats {
+				fmt.Fprintf(&b, "  - ~~%s~~\n", stat)
+			}
+			for _, stat := range change.AddedStats {
+				fmt.Fprintf(&b, "  - %s\n", stat)
+			}
+			for _, flag := range change.FlagChanges {
+				fmt.Fprintf(&b, "  - `%s`\n", flag)
+			}
+			if change.MasteryEffectsChanged {
+				b.WriteString("  - Mastery effects changed\n")
+			}
+		}
+	}
+	if len(d.Moved) > 0 {
+		fmt.Fprintf(&b, "\n## Moved nodes (%d)\n\n", len(d.Moved))
+		for _, change := range d.Moved {
+			fmt.Fprintf(&b, "- **%s** (%d): group %d orbit %d index %d → group %d orbit %d index %d\n",
+				change.Name, change.Hash,
+				change.OldPosition.Group, change.OldPosition.Orbit, change.OldPosition.OrbitIndex,
+				change.NewPosition.Group, change.NewPosition.Orbit, change.NewPosition.OrbitIndex)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func RunDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	in := fs.String("in", ".", "directory containing the atlastree/, skilltree/ and poe2tree/ exports")
+	kindName := fs.String("kind", "passives", "tree kind to compare: atlas, passives or poe2")
+	format := fs.String("format", "md", "output format: md or json")
+	svgOut := fs.String("svg", "", "also write an SVG of the new tree with the changes highlighted to this file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: treegen diff [flags] <old version> <new version>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two versions to compare")
+	}
+	if *format != "md" && *format != "json" {
+		return fmt.Errorf("unknown format %q, expected md or json", *format)
+	}
+	kind, err := GetTreeKind(*kindName)
+	if err != nil {
+		return err
+	}
+	oldFile := NewTreeFile(*in, kind, fs.Arg(0))
+	newFile := NewTreeFile(*in, kind, fs.Arg(1))
+	oldTree, err := LoadTree(oldFile.Path)
+	if err != nil {
+		return err
+	}
+	newTree, err := LoadTree(newFile.Path)
+	if err != nil {
+		return err
+	}
+
+	overlay := NewDiffOverlay(oldTree, newTree)
+	overlay.Diff.Kind = kind.Name
+	overlay.Diff.OldVersion = oldFile.Version
+	overlay.Diff.NewVersion = newFile.Version
+	if *svgOut != "" {
+		outFile, err := os.Create(*svgOut)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+		WriteSvg(outFile, newTree, DrawOptions{Diff: overlay, Style: DefaultStyle})
+	}
+
+	if *format == "json" {
+		return overlay.Diff.WriteJson(os.Stdout)
+	}
+	return overlay.Diff.WriteMarkdown(os.Stdout)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// returns the fixture and a copy with one node added, removed, renamed,
+// re-statted and moved
+func diffTrees(t *testing.T) (Tree, Tree) {
+	t.Helper()
+	oldTree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := "Fresh"
+	newTree.Nodes["600"] = Node{Skill: 600, Name: &fresh, Stats: []string{"+5 to Dexterity"}, Group: 6}
+	delete(newTree.Nodes, "500")
+	renamed := newTree.Nodes["101"]
+	intellect := "Intellect"
+	renamed.Name = &intellect
+	newTree.Nodes["101"] = renamed
+	changed := newTree.Nodes["103"]
+	changed.Stats = []string{"10% increased Spell Damage"}
+	changed.IsNotable = true
+	newTree.Nodes["103"] = changed
+	moved := newTree.Nodes["102"]
+	moved.OrbitIndex = 5
+	newTree.Nodes["102"] = moved
+	return oldTree, newTree
+}
+
+func TestDiffTrees(t *testing.T) {
+	oldTree, newTree := diffTrees(t)
+	diff := DiffTrees(oldTree, newTree)
+	want := TreeDiff{
+		Added:   []DiffNode{{Hash: 600, Name: "Fresh", Stats: []string{"+5 to Dexterity"}}},
+		Removed: []DiffNode{{Hash: 500, Name: "Lonely"}},
+		Renamed: []NodeChange{{Hash: 101, Name: "Intellect", OldName: "Intelligence"}},
+		Changed: []NodeChange{{
+			Hash:         103,
+			Name:         "Spell Damage",
+			RemovedStats: []string{"8% increased Spell Damage"},
+			AddedStats:   []string{"10% increased Spell Damage"},
+			FlagChanges:  []string{"isNotable: false -> true"},
+		}},
+		Moved: []NodeChange{{
+			Hash:        102,
+			Name:        "Arcane Focus",
+			OldPosition: &NodePosition{Group: 2, Orbit: 2, OrbitIndex: 4},
+			NewPosition: &NodePosition{Group: 2, Orbit: 2, OrbitIndex: 5},
+		}},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffTrees =\n%+v\nwant\n%+v", diff, want)
+	}
+	if diff.IsEmpty() {
+		t.Error("the diff is empty")
+	}
+	if diff := DiffTrees(oldTree, oldTree); !diff.IsEmpty() {
+		t.Errorf("a tree differs from itself: %+v", diff)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	oldTree, newTree := diffTrees(t)
+	diff := DiffTrees(oldTree, newTree)
+	diff.Kind, diff.OldVersion, diff.NewVersion = "passives", "3.25", "3.26"
+	var buffer bytes.Buffer
+	if err := diff.WriteMarkdown(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Passive tree changes from 3.25 to 3.26
+
+## Added nodes (1)
+
+- **Fresh** (600): +5 to Dexterity
+
+## Removed nodes (1)
+
+- **Lonely** (500)
+
+## Renamed nodes (1)
+
+- Intelligence → **Intellect** (101)
+
+## Changed nodes (1)
+
+- **Spell Damage** (103)
+  - ~~8% increased Spell Damage~~
+  - 10% increased Spell Damage
+  - ` + "`isNotable: false -> true`" + `
+
+## Moved nodes (1)
+
+- **Arcane Focus** (102): group 2 orbit 2 index 4 → group 2 orbit 2 index 5
+`
+	if buffer.String() != want {
+		t.Errorf("WriteMarkdown =\n%s\nwant\n%s", buffer.String(), want)
+	}
+
+	buffer.Reset()
+	empty := DiffTrees(oldTree, oldTree)
+	empty.Kind, empty.OldVersion, empty.NewVersion = "atlas", "3.25", "3.25"
+	if err := empty.WriteMarkdown(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Atlas tree changes from 3.25 to 3.25\n\nNo changes.\n"; buffer.String() != want {
+		t.Errorf("WriteMarkdown of an empty diff = %q, want %q", buffer.String(), want)
+	}
+}
+
+func TestWriteJson(t *testing.T) {
+	oldTree, newTree := diffTrees(t)
+	diff := DiffTrees(oldTree, newTree)
+	diff.Kind, diff.OldVersion, diff.NewVersion = "passives", "3.25", "3.26"
+	var buffer bytes.Buffer
+	if err := diff.WriteJson(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var decoded TreeDiff
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, diff) {
+		t.Errorf("decoded %+v, want %+v", decoded, diff)
+	}
+	// empty categories are written as empty lists rather than null
+	buffer.Reset()
+	if err := DiffTrees(oldTree, oldTree).WriteJson(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buffer.Bytes(), []byte(`"added": []`)) {
+		t.Errorf("empty diff written as %s", buffer.String())
+	}
+}
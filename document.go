@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	svg "github.com/ajstarks/svgo"
+)
+
+// DocumentOptions make an svg self-describing, with a title and metadata
+// naming the tree it was drawn from and a legend of the node classes
+type DocumentOptions struct {
+	Describe bool
+	Legend   bool
+	// filled in by ForFile
+	Title      string
+	Kind       string
+	Version    string
+	Source     string
+	SourceHash string
+}
+
+// DescribeFile returns the title and metadata of a tree file, which hashes
+// its contents
+func DescribeFile(file TreeFile) (DocumentOptions, error) {
+	data, err := os.ReadFile(file.Path)
+	if err != nil {
+		return DocumentOptions{}, err
+	}
+	hash := sha256.Sum256(data)
+	return DocumentOptions{
+		Title:      fmt.Sprintf("%s %s", file.Kind.Title, file.Version),
+		Kind:       file.Kind.Name,
+		Version:    file.Version,
+		Source:     filepath.Base(file.Path),
+		SourceHash: hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// ForFile returns the options describing the svg of a tree file
+func (o DrawOptions) ForFile(file TreeFile) (DrawOptions, error) {
+	if o.Document == nil {
+		return o, nil
+	}
+	document, err := DescribeFile(file)
+	if err != nil {
+		return o, err
+	}
+	return o.WithDescription(document), nil
+}
+
+// WithDescription fills the title and metadata into the document options
+func (o DrawOptions) WithDescription(description DocumentOptions) DrawOptions {
+	if o.Document == nil {
+		return o
+	}
+	description.Describe, description.Legend = o.Document.Describe, o.Document.Legend
+	o.Document = &description
+	return o
+}
+
+var releaseVersion = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+
+// returns the generator named in the metadata, with the version of released
+// builds only so that development builds do not change every svg they draw
+func GeneratorVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || !releaseVersion.MatchString(info.Main.Version) {
+		return "treegen"
+	}
+	return "treegen " + info.Main.Version
+}
+
+// writes the title and metadata elements, which must come first in the svg
+func DrawDescription(s *svg.SVG, document DocumentOptions) {
+	if document.Title != "" {
+		s.Title(document.Title)
+	}
+	attrs := []string{"id=\"tree-metadata\""}
+	for _, field := range [][2]string{
+		{"kind", document.Kind},
+		{"version", document.Version},
+		{"source", document.Source},
+		{"source-sha256", document.SourceHash},
+		{"generator", GeneratorVersion()},
+	} {
+		if field[1] != "" {
+			attrs = append(attrs, fmt.Sprintf("data-%s=\"%s\"", field[0], html.EscapeString(field[1])))
+		}
+	}
+	fmt.Fprintf(s.Writer, "<metadata %s></metadata>\n", strings.Join(attrs, " "))
+}
+
+type LegendEntry struct {
+	Label   string
+	Classes []string
+	Radius  int
+}
+
+// LegendEntries returns the node kinds shown in the legend of a tree, with
+// the classes and radius DrawNode gives them
+func LegendEntries(tree Tree, options DrawOptions) []LegendEntry {
+	entries := []LegendEntry{
+		{"Passive", nil, PassiveRadius},
+		{"Notable", nil, NotableRadius},
+		{"Keystone", []string{"keystone"}, KeystoneRadius},
+		{"Mastery", []string{"mastery"}, PassiveRadius},
+	}
+	if tree.Game == PoE2 {
+		entries = append(entries, LegendEntry{"Attribute", []string{"attribute"}, PassiveRadius})
+	}
+	entries = append(entries,
+		LegendEntry{"Ascendancy", []string{"ascendancy"}, PassiveRadius},
+		LegendEntry{"Isolated", []string{"isolated"}, PassiveRadius},
+	)
+	if options.Allocation != nil {
+		entries = append(entries, LegendEntry{"Allocated", []string{"allocated"}, PassiveRadius})
+	}
+	if options.Diff != nil {
+		entries = append(entries,
+			LegendEntry{"Added", []string{"added"}, PassiveRadius},
+			LegendEntry{"Removed", []string{"removed"}, PassiveRadius},
+			LegendEntry{"Changed", []string{"changed"}, PassiveRadius},
+			LegendEntry{"Moved", []string{"moved"}, PassiveRadius},
+		)
+	}
+	return entries
+}
+
+const (
+	legendPadding = 60
+	legendRow     = 180
+	legendWidth   = 900
+	// the font size of text.legend-title in the default style
+	legendTitleSize = 72
+)
+
+// draws the legend in the bottom left corner, the entries are circles with
+// the classes of the nodes so they follow the style
+func (d *TreeDrawer) DrawLegend() {
+	document := d.Options.Document
+	entries := LegendEntries(d.Tree, d.Options)
+	heading := "Legend"
+	if document.Title != "" {
+		heading = document.Title
+	}
+	minX, _, _, maxY := ViewBox(d.Tree, d.Options)
+	height := legendPadding*2 + legendRow*(len(entries)+1)
+	// text widths are estimated as for the labels
+	width := max(legendWidth, int(float64(len([]rune(heading)))*legendTitleSize*0.55)+2*legendPadding)
+	x, y := minX+legendPadding, maxY-legendPadding-height
+
+	d.s.Gid("legend")
+	d.s.Rect(x, y, width, height, "class=\"legend-background\"")
+	d.s.Text(x+legendPadding, y+legendPadding+legendRow/2, heading, "class=\"legend-title\" dominant-baseline=\"middle\"")
+	for i, entry := range entries {
+		cy := y + legendPadding + legendRow*(i+1) + legendRow/2
+		attrs := []string{}
+		if len(entry.Classes) > 0 {
+			attrs = append(attrs, fmt.Sprintf("class=\"%s\"", strings.Join(entry.Classes, " ")))
+		}
+		d.s.Circle(x+legendPadding+80, cy, entry.Radius, attrs...)
+		d.s.Text(x+legendPadding+200, cy, entry.Label, "class=\"legend-label\" dominant-baseline=\"middle\"")
+	}
+	d.s.Gend()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	svg "github.com/ajstarks/svgo"
+)
+
+func TestForFile(t *testing.T) {
+	kind, err := GetTreeKind("passives")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := NewTreeFile("testdata", kind, "3.25")
+	data, err := os.ReadFile(file.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(data)
+
+	options, err := DrawOptions{Document: &DocumentOptions{Legend: true}}.ForFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DocumentOptions{
+		Legend:     true,
+		Title:      "Passive tree 3.25",
+		Kind:       "passives",
+		Version:    "3.25",
+		Source:     "3.25.json",
+		SourceHash: hex.EncodeToString(hash[:]),
+	}
+	if *options.Document != want {
+		t.Errorf("ForFile = %+v, want %+v", *options.Document, want)
+	}
+
+	// options without a document are left alone, even for missing files
+	missing := NewTreeFile("testdata", kind, "9.99")
+	if options, err := (DrawOptions{Style: "x"}).ForFile(missing); err != nil || options.Document != nil || options.Style != "x" {
+		t.Errorf("ForFile without a document = %+v, %v", options, err)
+	}
+	if _, err := (DrawOptions{Document: &DocumentOptions{Describe: true}}).ForFile(missing); err == nil {
+		t.Error("ForFile described a missing file")
+	}
+}
+
+func TestDrawDescription(t *testing.T) {
+	var buffer bytes.Buffer
+	DrawDescription(svg.New(&buffer), DocumentOptions{
+		Title:      "Passive tree 3.25",
+		Kind:       "passives",
+		Version:    "3.25",
+		Source:     `"odd" <name>.json`,
+		SourceHash: "abc123",
+	})
+	out := buffer.String()
+	if !strings.HasPrefix(out, "<title>Passive tree 3.25</title>\n") {
+		t.Errorf("the title does not come first: %s", out)
+	}
+	want := `<metadata id="tree-metadata" data-kind="passives" data-version="3.25" data-source="&#34;odd&#34; &lt;name&gt;.json" data-source-sha256="abc123" data-generator="treegen"></metadata>`
+	if !strings.Contains(out, want) {
+		t.Errorf("metadata = %s, want %s", out, want)
+	}
+
+	// empty fields and the title are left out
+	buffer.Reset()
+	DrawDescription(svg.New(&buffer), DocumentOptions{Kind: "atlas"})
+	if out := buffer.String(); strings.Contains(out, "<title>") || strings.Contains(out, "data-version") {
+		t.Errorf("description of an empty document = %s", out)
+	}
+}
+
+func TestLegendEntries(t *testing.T) {
+	labels := func(entries []LegendEntry) []string {
+		result := make([]string, len(entries))
+		for i, entry := range entries {
+			result[i] = entry.Label
+		}
+		return result
+	}
+	tests := []struct {
+		name    string
+		game    Game
+		options DrawOptions
+		labels  []string
+	}{
+		{"tree", PoE1, DrawOptions{}, []string{"Passive", "Notable", "Keystone", "Mastery", "Ascendancy", "Isolated"}},
+		{"poe2", PoE2, DrawOptions{}, []string{"Passive", "Notable", "Keystone", "Mastery", "Attribute", "Ascendancy", "Isolated"}},
+		{"allocation and diff", PoE1, DrawOptions{Allocation: &Allocation{}, Diff: &DiffOverlay{}},
+			[]string{"Passive", "Notable", "Keystone", "Mastery", "Ascendancy", "Isolated", "Allocated", "Added", "Removed", "Changed", "Moved"}},
+	}
+	for _, test := range tests {
+		entries := LegendEntries(Tree{Game: test.game}, test.options)
+		if got := labels(entries); !slices.Equal(got, test.labels) {
+			t.Errorf("%s: legend %v, want %v", test.name, got, test.labels)
+		}
+	}
+	// the swatches have the radius and classes DrawNode gives the nodes
+	entries := LegendEntries(Tree{}, DrawOptions{})
+	if keystone := entries[2]; keystone.Radius != KeystoneRadius || !slices.Equal(keystone.Classes, []string{"keystone"}) {
+		t.Errorf("keystone entry = %+v", keystone)
+	}
+	if notable := entries[1]; notable.Radius != NotableRadius || len(notable.Classes) != 0 {
+		t.Errorf("notable entry = %+v", notable)
+	}
+}
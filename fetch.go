@@ -0,0 +1,270 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultFetchBaseURL = "https://github.com"
+
+// the data file names used by the exports over time and the extension they
+// are saved with, in order of preference
+var exportDataFiles = []struct {
+	Name      string
+	Extension string
+}{
+	{Name: "data.json", Extension: ".json"},
+	{Name: "data.txt", Extension: ".txt"},
+	{Name: "data", Extension: ".data"},
+}
+
+type Fetcher struct {
+	BaseURL   string
+	OutputDir string
+	Force     bool
+	Client    *http.Client
+}
+
+type FetchResult struct {
+	Kind    TreeKind
+	Tag     string
+	Version string
+	Path    string
+	Skipped bool
+	Err     error
+}
+
+// ListTags lists the tags of a repository using the git smart http protocol,
+// the same way git ls-remote does
+func (f *Fetcher) ListTags(repo string) ([]string, error) {
+	url := fmt.Sprintf("%s/%s.git/info/refs?service=git-upload-pack", strings.TrimRight(f.BaseURL, "/"), repo)
+	resp, err := f.Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list tags of %s: %s", repo, resp.Status)
+	}
+
+	tags := make([]string, 0)
+	reader := bufio.NewReader(resp.Body)
+	for {
+		header := make([]byte, 4)
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		length, err := strconv.ParseUint(string(header), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pkt-line header %q", header)
+		}
+		if length < 4 {
+			continue
+		}
+		line := make([]byte, length-4)
+		_, err = io.ReadFull(reader, line)
+		if err != nil {
+			return nil, err
+		}
+		ref, _, _ := bytes.Cut(bytes.TrimRight(line, "\n"), []byte{0})
+		_, name, found := bytes.Cut(ref, []byte(" "))
+		if !found || !bytes.HasPrefix(name, []byte("refs/tags/")) || bytes.HasSuffix(name, []byte("^{}")) {
+			continue
+		}
+		tags = append(tags, strings.TrimPrefix(string(name), "refs/tags/"))
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return CompareVersions(tags[i], tags[j]) < 0
+	})
+	return tags, nil
+}
+
+// maps a tag like 3.25.0 or 3.25.0-atlas to the version 3.25, only tags of
+// major releases, ending in 0, are downloaded
+func TagVersion(tag string) (string, bool) {
+	version := strings.ReplaceAll(tag, "-atlas", "")
+	if !strings.HasSuffix(version, "0") {
+		return "", false
+	}
+	return strings.TrimSuffix(version, ".0"), true
+}
+
+func (f *Fetcher) ExistingFile(kind TreeKind, version string) string {
+	for _, dataFile := range exportDataFiles {
+		path := filepath.Join(f.OutputDir, kind.InputDir, version+dataFile.Extension)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func (f *Fetcher) FetchTag(kind TreeKind, tag string, version string) FetchResult {
+	result := FetchResult{Kind: kind, Tag: tag, Version: version}
+	if existing := f.ExistingFile(kind, version); existing != "" && !f.Force {
+		result.Path = existing
+		result.Skipped = true
+		return result
+	}
+
+	url := fmt.Sprintf("%s/%s/archive/refs/tags/%s.zip", strings.TrimRight(f.BaseURL, "/"), kind.Repo, tag)
+	resp, err := f.Client.Get(url)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		result.Err = fmt.Errorf("failed to download %s: %s", url, resp.Status)
+		return result
+	}
+	archive, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	data, extension, err := extractExportData(archive)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if extension == ".json" {
+		var compact bytes.Buffer
+		err = json.Compact(&compact, data)
+		if err != nil {
+			result.Err = fmt.Errorf("invalid data.json: %w", err)
+			return result
+		}
+		data = compact.Bytes()
+	}
+
+	err = os.MkdirAll(filepath.Join(f.OutputDir, kind.InputDir), os.ModePerm)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Path = filepath.Join(f.OutputDir, kind.InputDir, version+extension)
+	result.Err = os.WriteFile(result.Path, data, 0644)
+	return result
+}
+
+// returns the contents and extension of the data file in the top level
+// folder of a github source archive
+func extractExportData(archive []byte) ([]byte, string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid zip archive: %w", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range reader.File {
+		dir, name := path.Split(file.Name)
+		if strings.Count(dir, "/") == 1 {
+			files[name] = file
+		}
+	}
+	for _, dataFile := range exportDataFiles {
+		file, exists := files[dataFile.Name]
+		if !exists {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		return data, dataFile.Extension, err
+	}
+	return nil, "", fmt.Errorf("no data file found in archive")
+}
+
+func (f *Fetcher) FetchKind(kind TreeKind, versions []string) ([]FetchResult, error) {
+	tags, err := f.ListTags(kind.Repo)
+	if err != nil {
+		return nil, err
+	}
+	cfg := Config{Versions: versions}
+	results := make([]FetchResult, 0)
+	for _, tag := range tags {
+		version, ok := TagVersion(tag)
+		if !ok || !cfg.MatchesVersion(version) {
+			continue
+		}
+		results = append(results, f.FetchTag(kind, tag, version))
+	}
+	return results, nil
+}
+
+func RunFetch(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	in := fs.String("in", ".", "directory to save the atlastree/ and skilltree/ exports to")
+	kind := fs.String("kind", "all", "tree kind to download: atlas, passives or all")
+	versions := fs.String("version", "", "comma separated list of versions to download, e.g. 3.25,3.26 (default all)")
+	baseURL := fs.String("base-url", DefaultFetchBaseURL, "base url of the git host serving the export repositories")
+	force := fs.Bool("force", false, "download versions that already exist")
+	timeout := fs.Duration("timeout", 2*time.Minute, "timeout for each request")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	kinds, err := ParseTreeKinds(*kind)
+	if err != nil {
+		return err
+	}
+
+	fetcher := &Fetcher{
+		BaseURL:   *baseURL,
+		OutputDir: *in,
+		Force:     *force,
+		Client:    &http.Client{Timeout: *timeout},
+	}
+	failed := 0
+	for _, kind := range kinds {
+		if kind.Repo == "" {
+			fmt.Printf("Skipping %s, its exports are not published\n", kind.Name)
+			continue
+		}
+		fmt.Printf("Fetching %s exports from %s\n", kind.Name, kind.Repo)
+		results, err := fetcher.FetchKind(kind, ParseVersions(*versions))
+		if err != nil {
+			fmt.Printf("  FAILED to list tags: %v\n", err)
+			failed++
+			continue
+		}
+		for _, result := range results {
+			switch {
+			case result.Err != nil:
+				fmt.Printf("  %-16s FAILED: %v\n", result.Tag, result.Err)
+				failed++
+			case result.Skipped:
+				fmt.Printf("  %-16s skipped, %s exists\n", result.Tag, result.Path)
+			default:
+				fmt.Printf("  %-16s saved to %s\n", result.Tag, result.Path)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d downloads failed", failed)
+	}
+	fmt.Println("Done!")
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func pktLine(s string) string {
+	return fmt.Sprintf("%04x%s", len(s)+4, s)
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	w := zip.NewWriter(&buffer)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+const fetchRepo = "grindinggear/skilltree-export"
+
+// serves the refs and source archives of the skilltree-export repository
+// like github does
+func newExportServer(t *testing.T) *httptest.Server {
+	sha := strings.Repeat("a", 40)
+	refs := pktLine("# service=git-upload-pack\n") + "0000" +
+		pktLine(sha+" HEAD\x00multi_ack thin-pack side-band symref=HEAD:refs/heads/master\n") +
+		pktLine(sha+" refs/heads/master\n") +
+		pktLine(sha+" refs/tags/3.25.0\n") +
+		pktLine(sha+" refs/tags/3.25.0^{}\n") +
+		pktLine(sha+" refs/tags/3.25.1\n") +
+		pktLine(sha+" refs/tags/3.26.0\n") +
+		pktLine(sha+" refs/tags/3.9.0\n") +
+		pktLine(sha+" refs/tags/3.9.0^{}\n") +
+		"0000"
+	archives := map[string][]byte{
+		"3.25.0": zipArchive(t, map[string]string{
+			"skilltree-export-3.25.0/data.json":        "{\n  \"tree\": \"Default\",\n  \"nodes\": {}\n}\n",
+			"skilltree-export-3.25.0/data.txt":         "var passiveSkillTreeData = {};",
+			"skilltree-export-3.25.0/assets/data.json": "{}",
+		}),
+		"3.26.0": zipArchive(t, map[string]string{
+			"skilltree-export-3.26.0/data.txt": "var passiveSkillTreeData = {\"characterData\": {}};",
+		}),
+		"3.9.0": zipArchive(t, map[string]string{
+			"skilltree-export-3.9.0/README.md": "no export",
+		}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /"+fetchRepo+".git/info/refs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("service") != "git-upload-pack" {
+			http.Error(w, "missing service", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(refs))
+	})
+	mux.HandleFunc("GET /"+fetchRepo+"/archive/refs/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		archive, exists := archives[strings.TrimSuffix(r.PathValue("tag"), ".zip")]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestFetcher(t *testing.T, baseURL string) *Fetcher {
+	return &Fetcher{BaseURL: baseURL, OutputDir: t.TempDir(), Client: http.DefaultClient}
+}
+
+func TestListTags(t *testing.T) {
+	server := newExportServer(t)
+	defer server.Close()
+	tags, err := newTestFetcher(t, server.URL).ListTags(fetchRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// peeled tags and branches are skipped and the tags sorted by version
+	want := []string{"3.9.0", "3.25.0", "3.25.1", "3.26.0"}
+	if !slices.Equal(tags, want) {
+		t.Errorf("ListTags = %v, want %v", tags, want)
+	}
+
+	_, err = newTestFetcher(t, server.URL).ListTags("grindinggear/missing")
+	if err == nil {
+		t.Error("ListTags of a missing repository should fail")
+	}
+}
+
+func TestListTagsInvalidHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("zzzz not a pkt-line"))
+	}))
+	defer server.Close()
+	_, err := newTestFetcher(t, server.URL).ListTags(fetchRepo)
+	if err == nil || !strings.Contains(err.Error(), "invalid pkt-line header") {
+		t.Errorf("got error %v, want an invalid pkt-line header", err)
+	}
+}
+
+func TestTagVersion(t *testing.T) {
+	tests := []struct {
+		tag     string
+		version string
+		ok      bool
+	}{
+		{"3.25.0", "3.25", true},
+		{"3.25.0-atlas", "3.25", true},
+		{"3.10.0", "3.10", true},
+		{"3.25.1", "", false},
+		{"3.25.1-atlas", "", false},
+	}
+	for _, test := range tests {
+		version, ok := TagVersion(test.tag)
+		if version != test.version || ok != test.ok {
+			t.Errorf("TagVersion(%q) = %q, %v, want %q, %v", test.tag, version, ok, test.version, test.ok)
+		}
+	}
+}
+
+func TestFetchTag(t *testing.T) {
+	server := newExportServer(t)
+	defer server.Close()
+	fetcher := newTestFetcher(t, server.URL)
+	kind, _ := GetTreeKind("passives")
+
+	// data.json is preferred and minified, nested files are ignored
+	result := fetcher.FetchTag(kind, "3.25.0", "3.25")
+	if result.Err != nil || result.Skipped {
+		t.Fatalf("FetchTag(3.25.0) = %+v", result)
+	}
+	data, err := os.ReadFile(filepath.Join(fetcher.OutputDir, "skilltree", "3.25.json"))
+	if err != nil || string(data) != `{"tree":"Default","nodes":{}}` {
+		t.Errorf("saved %q, %v", data, err)
+	}
+
+	result = fetcher.FetchTag(kind, "3.26.0", "3.26")
+	if result.Err != nil || filepath.Base(result.Path) != "3.26.txt" {
+		t.Errorf("FetchTag(3.26.0) = %+v", result)
+	}
+
+	result = fetcher.FetchTag(kind, "3.9.0", "3.9")
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "no data file") {
+		t.Errorf("FetchTag(3.9.0) = %+v, want a missing data file", result)
+	}
+
+	result = fetcher.FetchTag(kind, "3.27.0", "3.27")
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "404") {
+		t.Errorf("FetchTag(3.27.0) = %+v, want a 404", result)
+	}
+
+	// existing versions are only downloaded again with Force
+	result = fetcher.FetchTag(kind, "3.25.0", "3.25")
+	if !result.Skipped {
+		t.Errorf("FetchTag of an existing version = %+v, want skipped", result)
+	}
+	fetcher.Force = true
+	result = fetcher.FetchTag(kind, "3.25.0", "3.25")
+	if result.Err != nil || result.Skipped {
+		t.Errorf("forced FetchTag = %+v", result)
+	}
+}
+
+func TestExtractExportData(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		data      string
+		extension string
+	}{
+		{"json", map[string]string{"export-1/data.json": "{}", "export-1/data.txt": "txt"}, "{}", ".json"},
+		{"txt", map[string]string{"export-1/data.txt": "txt", "export-1/data": "data"}, "txt", ".txt"},
+		{"data", map[string]string{"export-1/data": "data"}, "data", ".data"},
+		{"nested only", map[string]string{"export-1/old/data.json": "{}"}, "", ""},
+		{"top level only", map[string]string{"data.json": "{}"}, "", ""},
+	}
+	for _, test := range tests {
+		data, extension, err := extractExportData(zipArchive(t, test.files))
+		if test.extension == "" {
+			if err == nil {
+				t.Errorf("%s: extracted %q, want an error", test.name, data)
+			}
+			continue
+		}
+		if err != nil || string(data) != test.data || extension != test.extension {
+			t.Errorf("%s: extracted %q, %q, %v", test.name, data, extension, err)
+		}
+	}
+	_, _, err := extractExportData([]byte("not a zip"))
+	if err == nil {
+		t.Error("extractExportData accepted an invalid archive")
+	}
+}
+
+func TestRunFetch(t *testing.T) {
+	server := newExportServer(t)
+	defer server.Close()
+	dir := t.TempDir()
+
+	err := RunFetch([]string{"-in", dir, "-kind", "passives", "-version", "3.25,3.26", "-base-url", server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"3.25.json", "3.26.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, "skilltree", name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// any failed download makes the command fail, which exits non-zero
+	err = RunFetch([]string{"-in", dir, "-kind", "passives", "-base-url", server.URL})
+	if err == nil || err.Error() != "1 downloads failed" {
+		t.Errorf("got error %v, want 1 failed download", err)
+	}
+}
+
+func TestRunFetchServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	err := RunFetch([]string{"-in", t.TempDir(), "-kind", "atlas,passives", "-base-url", server.URL})
+	if err == nil || err.Error() != "2 downloads failed" {
+		t.Errorf("got error %v, want both tag listings to fail", err)
+	}
+}
@@ -2,4 +2,4 @@ module treegen
 
 go 1.25.3
 
-require github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b // indirect
+require github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Graph is the passive tree as an undirected graph that only contains the
+// edges a character can path along. Masteries have no edges, class starts can
+// only be used as the root of a path and ascendancy nodes only connect to
+// nodes of the same ascendancy.
+type Graph struct {
+	Tree      Tree
+	Nodes     map[int]Node
+	adjacency map[int][]int
+}
+
+func NewGraph(tree Tree) *Graph {
+	g := &Graph{
+		Tree:      tree,
+		Nodes:     numericNodes(tree),
+		adjacency: make(map[int][]int, len(tree.Nodes)),
+	}
+	for hash, node := range g.Nodes {
+		for _, neighbourId := range append(append([]string{}, node.Out...), node.In...) {
+			neighbourHash, err := strconv.Atoi(neighbourId)
+			if err != nil {
+				continue
+			}
+			neighbour, exists := g.Nodes[neighbourHash]
+			if !exists || !CanPathBetween(node, neighbour) {
+				continue
+			}
+			g.addEdge(hash, neighbourHash)
+		}
+	}
+	for hash := range g.adjacency {
+		sort.Ints(g.adjacency[hash])
+	}
+	return g
+}
+
+func (g *Graph) addEdge(a, b int) {
+	for _, existing := range g.adjacency[a] {
+		if existing == b {
+			return
+		}
+	}
+	g.adjacency[a] = append(g.adjacency[a], b)
+	g.adjacency[b] = append(g.adjacency[b], a)
+}
+
+func CanPathBetween(n1 Node, n2 Node) bool {
+	if n1.IsMastery || n2.IsMastery || n1.IsProxy || n2.IsProxy {
+		return false
+	}
+	if n1.ClassStartIndex != nil && n2.ClassStartIndex != nil {
+		return false
+	}
+	if (n1.AscendancyName == nil) != (n2.AscendancyName == nil) {
+		return false
+	}
+	return n1.AscendancyName == nil || *n1.AscendancyName == *n2.AscendancyName
+}
+
+func (g *Graph) Neighbours(hash int) []int {
+	return g.adjacency[hash]
+}
+
+func (g *Graph) ClassStart(classIndex int) (int, error) {
+	for hash, node := range g.Nodes {
+		if node.ClassStartIndex != nil && *node.ClassStartIndex == classIndex {
+			return hash, nil
+		}
+	}
+	return 0, fmt.Errorf("no start node for class %d", classIndex)
+}
+
+func (g *Graph) AscendancyStart(ascendancy string) (int, error) {
+	for hash, node := range g.Nodes {
+		if node.IsAscendancyStart && node.AscendancyName != nil && *node.AscendancyName == ascendancy {
+			return hash, nil
+		}
+	}
+	return 0, fmt.Errorf("no start node for ascendancy %q", ascendancy)
+}
+
+func (g *Graph) ClassIndex(name string) (int, error) {
+	for i, class := range g.Tree.Classes {
+		if strings.EqualFold(class.Name, name) {
+			return i, nil
+		}
+		for _, ascendancy := range class.Ascendancies {
+			if strings.EqualFold(ascendancy.Name, name) {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown class %q", name)
+}
+
+// resolves a node given either by its hash or by its name
+func (g *Graph) FindNode(ref string) (int, error) {
+	if hash, err := strconv.Atoi(ref); err == nil {
+		if _, exists := g.Nodes[hash]; !exists {
+			return 0, fmt.Errorf("unknown node %d", hash)
+		}
+		return hash, nil
+	}
+	matches := make([]int, 0)
+	for hash, node := range g.Nodes {
+		if node.Name != nil && strings.EqualFold(*node.Name, ref) {
+			matches = append(matches, hash)
+		}
+	}
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("unknown node %q", ref)
+	}
+	if len(matches) > 1 {
+		sort.Ints(matches)
+		return 0, fmt.Errorf("node name %q is ambiguous, matches %v", ref, matches)
+	}
+	return matches[0], nil
+}
+
+// Roots returns the nodes a path may start from for a class, which are the
+// class start and the start of the chosen ascendancy
+func (g *Graph) Roots(classIndex int, ascendancy string) ([]int, error) {
+	start, err := g.ClassStart(classIndex)
+	if err != nil {
+		return nil, err
+	}
+	roots := []int{start}
+	if ascendancy != "" {
+		ascendancyStart, err := g.AscendancyStart(ascendancy)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, ascendancyStart)
+	}
+	return roots, nil
+}
+
+// StartNodes returns the roots of the paths to targets and the ascendancy
+// they are in. Without a chosen ascendancy, paths to ascendancy nodes start at
+// the start of the ascendancy of the targets.
+func (g *Graph) StartNodes(classIndex int, ascendancy string, targets []int) ([]int, string, error) {
+	if ascendancy == "" {
+		for _, target := range targets {
+			node := g.Nodes[target]
+			if node.AscendancyName == nil || *node.AscendancyName == ascendancy {
+				continue
+			}
+			if ascendancy != "" {
+				return nil, "", fmt.Errorf("targets are in both the %s and %s ascendancies", ascendancy, *node.AscendancyName)
+			}
+			ascendancy = *node.AscendancyName
+		}
+	}
+	roots, err := g.Roots(classIndex, ascendancy)
+	return roots, ascendancy, err
+}
+
+// ShortestPath returns the nodes that need to be allocated to connect target to
+// any of the allocated nodes, ordered from the allocated set to the target. The
+// length of the path is its cost in points. Masteries are reached through any
+// allocated node of their group.
+func (g *Graph) ShortestPath(allocated []int, target int) ([]int, error) {
+	targetNode, exists := g.Nodes[target]
+	if !exists {
+		return nil, fmt.Errorf("unknown node %d", target)
+	}
+	sources := make(map[int]bool, len(allocated))
+	for _, hash := range allocated {
+		sources[hash] = true
+	}
+	if sources[target] {
+		return []int{}, nil
+	}
+	if targetNode.IsMastery {
+		return g.masteryPath(allocated, target)
+	}
+
+	previous := make(map[int]int)
+	queue := make([]int, 0, len(allocated))
+	for _, hash := range allocated {
+		if _, exists := g.Nodes[hash]; exists {
+			queue = append(queue, hash)
+			previous[hash] = hash
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, neighbour := range g.adjacency[current] {
+			if _, visited := previous[neighbour]; visited {
+				continue
+			}
+			neighbourNode := g.Nodes[neighbour]
+			if neighbourNode.ClassStartIndex != nil {
+				continue
+			}
+			previous[neighbour] = current
+			if neighbour == target {
+				return g.unwind(previous, target), nil
+			}
+			queue = append(queue, neighbour)
+		}
+	}
+	return nil, fmt.Errorf("node %d is not reachable", target)
+}
+
+func (g *Graph) unwind(previous map[int]int, target int) []int {
+	path := []int{}
+	for current := target; previous[current] != current; current = previous[current] {
+		path = append(path, current)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func (g *Graph) masteryPath(allocated []int, mastery int) ([]int, error) {
+	group := g.Tree.Groups[strconv.Itoa(g.Nodes[mastery].Group)]
+	var best []int
+	for _, nodeid := range group.Nodes {
+		hash, err := strconv.Atoi(nodeid)
+		if err != nil || hash == mastery || g.Nodes[hash].IsMastery {
+			continue
+		}
+		path, err := g.ShortestPath(allocated, hash)
+		if err != nil {
+			continue
+		}
+		if best == nil || len(path) < len(best) {
+			best = path
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("mastery %d is not reachable", mastery)
+	}
+	return append(best, mastery), nil
+}
+
+// PathFromClass returns the shortest path from the class start to target,
+// or from the start of the target's ascendancy for ascendancy nodes
+func (g *Graph) PathFromClass(classIndex int, target int) ([]int, error) {
+	if _, exists := g.Nodes[target]; !exists {
+		return nil, fmt.Errorf("unknown node %d", target)
+	}
+	roots, _, err := g.StartNodes(classIndex, "", []int{target})
+	if err != nil {
+		return nil, err
+	}
+	return g.ShortestPath(roots, target)
+}
+
+func (g *Graph) NodeName(hash int) string {
+	return nodeName(g.Nodes[hash], hash)
+}
+
+func RunPath(args []string) error {
+	fs, config := NewFlagSet("path")
+	className := fs.String("class", "", "class or ascendancy to start from")
+	ascendancy := fs.String("ascendancy", "", "allocated ascendancy")
+	nodes := fs.String("nodes", "", "comma separated list of already allocated node hashes")
+	to := fs.String("to", "", "comma separated list of target node hashes or names")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	file, err := SelectTreeFile(cfg)
+	if err != nil {
+		return err
+	}
+	tree, err := LoadTree(file.Path)
+	if err != nil {
+		return err
+	}
+	graph := NewGraph(tree)
+
+	classIndex, err := graph.ClassIndex(*className)
+	if err != nil {
+		return err
+	}
+	allocated, err := ParseNodeList(*nodes)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range strings.Split(*to, ",") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		target, err := graph.FindNode(ref)
+		if err != nil {
+			return err
+		}
+		roots, _, err := graph.StartNodes(classIndex, *ascendancy, []int{target})
+		if err != nil {
+			return err
+		}
+		path, err := graph.ShortestPath(append(roots, allocated...), target)
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(path))
+		for i, hash := range path {
+			names[i] = graph.NodeName(hash)
+		}
+		fmt.Printf("%s (%d): %d points\n  %s\n", graph.NodeName(target), target, len(path), strings.Join(names, " -> "))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCanPathBetween(t *testing.T) {
+	witch, scion := 1, 0
+	occultist, elementalist := "Occultist", "Elementalist"
+	tests := []struct {
+		name   string
+		n1, n2 Node
+		want   bool
+	}{
+		{"passives", Node{}, Node{IsNotable: true}, true},
+		{"class start", Node{ClassStartIndex: &witch}, Node{}, true},
+		{"two class starts", Node{ClassStartIndex: &witch}, Node{ClassStartIndex: &scion}, false},
+		{"mastery", Node{}, Node{IsMastery: true}, false},
+		{"proxy", Node{IsProxy: true}, Node{}, false},
+		{"same ascendancy", Node{AscendancyName: &occultist}, Node{AscendancyName: &occultist}, true},
+		{"other ascendancy", Node{AscendancyName: &occultist}, Node{AscendancyName: &elementalist}, false},
+		{"ascendancy and tree", Node{AscendancyName: &occultist}, Node{}, false},
+	}
+	for _, test := range tests {
+		if got := CanPathBetween(test.n1, test.n2); got != test.want {
+			t.Errorf("%s: CanPathBetween = %v, want %v", test.name, got, test.want)
+		}
+		if got := CanPathBetween(test.n2, test.n1); got != test.want {
+			t.Errorf("%s: CanPathBetween reversed = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hang the lonely node off the witch start and the mastery between two passives
+	lonely := tree.Nodes["500"]
+	lonely.In = []string{"100"}
+	tree.Nodes["500"] = lonely
+	mastery := tree.Nodes["110"]
+	mastery.In, mastery.Out = []string{"102"}, []string{"104"}
+	tree.Nodes["110"] = mastery
+	graph := NewGraph(tree)
+
+	tests := []struct {
+		name      string
+		allocated []int
+		target    int
+		path      []int
+	}{
+		{"from the class start", []int{100}, 104, []int{101, 102, 103, 104}},
+		{"from an allocated set", []int{100, 101, 102}, 104, []int{103, 104}},
+		{"allocated target", []int{100, 101}, 101, []int{}},
+		{"class start as the root", []int{100}, 500, []int{500}},
+		// masteries are reached through the nearest allocated node of their group
+		{"mastery", []int{100}, 110, []int{101, 110}},
+		{"mastery next to the allocation", []int{100, 101, 102}, 110, []int{110}},
+		{"ascendancy start", []int{200}, 201, []int{201}},
+	}
+	for _, test := range tests {
+		path, err := graph.ShortestPath(test.allocated, test.target)
+		if err != nil || !slices.Equal(path, test.path) {
+			t.Errorf("%s: ShortestPath(%v, %d) = %v, %v, want %v", test.name, test.allocated, test.target, path, err, test.path)
+		}
+	}
+
+	unreachable := []struct {
+		name      string
+		allocated []int
+		target    int
+	}{
+		// the class start is only a root, it does not connect 101 to 500
+		{"through the class start", []int{101}, 500},
+		{"unknown node", []int{100}, 999},
+		{"ascendancy from the tree", []int{100}, 201},
+		{"other ascendancy", []int{200}, 301},
+	}
+	for _, test := range unreachable {
+		if path, err := graph.ShortestPath(test.allocated, test.target); err == nil {
+			t.Errorf("%s: ShortestPath(%v, %d) = %v, want an error", test.name, test.allocated, test.target, path)
+		}
+	}
+	// masteries have no edges, even when the export connects them
+	if slices.Contains(graph.Neighbours(102), 110) || len(graph.Neighbours(110)) != 0 {
+		t.Errorf("mastery neighbours %v, 102 neighbours %v", graph.Neighbours(110), graph.Neighbours(102))
+	}
+	if path, _ := graph.ShortestPath([]int{100, 101, 102}, 104); !slices.Equal(path, []int{103, 104}) {
+		t.Errorf("path to 104 goes through the mastery: %v", path)
+	}
+}
+
+func TestStartNodes(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := NewGraph(tree)
+	tests := []struct {
+		name       string
+		ascendancy string
+		targets    []int
+		roots      []int
+		want       string
+	}{
+		{"tree targets", "", []int{102, 104}, []int{100}, ""},
+		{"chosen ascendancy", "Elementalist", []int{102}, []int{100, 300}, "Elementalist"},
+		{"ascendancy of the targets", "", []int{102, 201}, []int{100, 200}, "Occultist"},
+	}
+	for _, test := range tests {
+		roots, ascendancy, err := graph.StartNodes(1, test.ascendancy, test.targets)
+		if err != nil || !slices.Equal(roots, test.roots) || ascendancy != test.want {
+			t.Errorf("%s: StartNodes = %v, %q, %v, want %v, %q", test.name, roots, ascendancy, err, test.roots, test.want)
+		}
+	}
+
+	_, _, err = graph.StartNodes(1, "", []int{201, 301})
+	if err == nil || !strings.Contains(err.Error(), "both the Occultist and Elementalist ascendancies") {
+		t.Errorf("targets in two ascendancies: %v", err)
+	}
+	if _, _, err := graph.StartNodes(0, "", []int{102}); err == nil {
+		t.Error("StartNodes found a start for the scion, which the tree does not have")
+	}
+}
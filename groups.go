@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type GroupOptions struct {
+	// directory the background sprite sheets are referenced from, relative
+	// to the svg, backgrounds are not drawn if empty
+	AssetDir string
+	// index into the tree's image zoom levels, negative for the largest
+	ZoomLevel int
+	// draws a ring for every orbit of a group
+	Orbits bool
+}
+
+func SortedGroupIds(tree Tree) []string {
+	groupids := make([]string, 0, len(tree.Groups))
+	for groupid := range tree.Groups {
+		groupids = append(groupids, groupid)
+	}
+	sort.Slice(groupids, func(i, j int) bool {
+		intI, _ := strconv.Atoi(groupids[i])
+		intJ, _ := strconv.Atoi(groupids[j])
+		return intI < intJ
+	})
+	return groupids
+}
+
+// draws the group backgrounds and orbit rings, both below the connections
+func (d *TreeDrawer) DrawGroups() {
+	options := d.Options.Groups
+	groupids := SortedGroupIds(d.Tree)
+	if options.AssetDir != "" {
+		d.s.Gid("backgrounds")
+		d.DrawBackgrounds(groupids)
+		d.s.Gend()
+	}
+	if options.Orbits {
+		d.s.Gid("orbits")
+		for _, groupid := range groupids {
+			group := d.Tree.Groups[groupid]
+			if !group.IsProxy {
+				d.DrawGroup(group)
+			}
+		}
+		d.s.Gend()
+	}
+}
+
+func (d *TreeDrawer) DrawBackgrounds(groupids []string) {
+	options := d.Options.Groups
+	zoom := d.Tree.ImageZoom(options.ZoomLevel)
+	sprite, exists := d.Tree.SpriteSheet("groupBackground", options.ZoomLevel)
+	if !exists {
+		return
+	}
+	patterns := make(map[string]string)
+	d.s.Def()
+	for _, groupid := range groupids {
+		image := d.Tree.Groups[groupid].Background.Image
+		if _, exists := patterns[image]; exists {
+			continue
+		}
+		coords, exists := sprite.Coords[image]
+		if !exists {
+			continue
+		}
+		patterns[image] = fmt.Sprintf("background-%d", len(patterns))
+		d.SpritePattern(patterns[image], sprite, coords, options.AssetDir, "none")
+	}
+	d.s.DefEnd()
+
+	for _, groupid := range groupids {
+		group := d.Tree.Groups[groupid]
+		id, exists := patterns[group.Background.Image]
+		if group.IsProxy || !exists {
+			continue
+		}
+		// the sprites are scaled down by the zoom level
+		coords := sprite.Coords[group.Background.Image]
+		w := int(float64(coords.W) / zoom)
+		h := int(float64(coords.H) / zoom)
+		x, y := int(group.X)-w/2, int(group.Y)
+		fill := fmt.Sprintf("style=\"fill:url(#%s)\"", id)
+		if !group.Background.IsHalfImage {
+			d.s.Rect(x, y-h/2, w, h, fill)
+			continue
+		}
+		// half images show the upper half and are mirrored for the lower one
+		d.s.Rect(x, y-h, w, h, fill)
+		d.s.Rect(x, -y-h, w, h, fill, "transform=\"scale(1,-1)\"")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDrawBackgrounds(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	half := tree.Groups["2"]
+	half.Y = 100
+	half.Background = Background{Image: "PSGroupBackground3", IsHalfImage: true}
+	tree.Groups["2"] = half
+	full := tree.Groups["3"]
+	full.Background = Background{Image: "PSGroupBackground1"}
+	tree.Groups["3"] = full
+	sprite := Sprite{
+		FileName: "group-background-3.png",
+		W:        200,
+		H:        100,
+		Coords: map[string]SpriteCoords{
+			"PSGroupBackground1": {X: 0, Y: 0, W: 100, H: 50},
+			"PSGroupBackground3": {X: 100, Y: 0, W: 100, H: 50},
+		},
+	}
+	tree.Sprites = map[string]map[string]Sprite{"groupBackground": {"0.3835": sprite}}
+
+	svg := renderSvg(t, tree, DrawOptions{Groups: &GroupOptions{AssetDir: "assets", ZoomLevel: -1}})
+	// the sprites of 100x50 are scaled up to 260x130 by the zoom level
+	for _, rect := range []string{
+		`<rect x="1870" y="-65" width="260" height="130" style="fill:url(#background-1)" />`,
+		// the upper half ends on the group centre and is mirrored below it
+		`<rect x="870" y="-30" width="260" height="130" style="fill:url(#background-0)" />`,
+		`<rect x="870" y="-230" width="260" height="130" style="fill:url(#background-0)" transform="scale(1,-1)" />`,
+	} {
+		if !strings.Contains(svg, rect) {
+			t.Errorf("missing %s in\n%s", rect, svg)
+		}
+	}
+	if count := strings.Count(svg, "<rect "); count != 3 {
+		t.Errorf("drew %d background rects, want 3", count)
+	}
+
+	// without an asset directory only the orbits are drawn
+	svg = renderSvg(t, tree, DrawOptions{Groups: &GroupOptions{Orbits: true}})
+	if strings.Contains(svg, "<rect ") || !strings.Contains(svg, `<g id="orbits">`) {
+		t.Errorf("drew backgrounds without assets or no orbits in\n%s", svg)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"treegen/internal/shareurl"
+)
+
+// a single build of the input file, either a plain list of node hashes or an
+// object with the class, ascendancy and nodes or a passive tree share url
+type HeatmapEntry struct {
+	Class      string `json:"class,omitempty"`
+	Ascendancy string `json:"ascendancy,omitempty"`
+	Nodes      []int  `json:"nodes,omitempty"`
+	Url        string `json:"url,omitempty"`
+}
+
+type Heatmap struct {
+	Class      string      `json:"class,omitempty"`
+	Ascendancy string      `json:"ascendancy,omitempty"`
+	Builds     int         `json:"builds"`
+	Counts     map[int]int `json:"counts"`
+	max        int
+}
+
+func LoadHeatmapEntries(fileName string) ([]HeatmapEntry, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries := make([]HeatmapEntry, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		entry := HeatmapEntry{}
+		if strings.HasPrefix(text, "[") {
+			err = json.Unmarshal([]byte(text), &entry.Nodes)
+		} else {
+			err = json.Unmarshal([]byte(text), &entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", fileName, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// resolves share urls into class, ascendancy and nodes
+func (e HeatmapEntry) Resolve(tree Tree) (HeatmapEntry, error) {
+	if e.Url == "" {
+		return e, nil
+	}
+	decoded, err := shareurl.Decode(e.Url)
+	if err != nil {
+		return e, err
+	}
+	build := PassiveBuild{decoded}
+	class, err := build.Class(tree)
+	if err != nil {
+		return e, err
+	}
+	ascendancy, err := build.AscendancyName(tree)
+	if err != nil {
+		return e, err
+	}
+	return HeatmapEntry{Class: class.Name, Ascendancy: ascendancy, Nodes: build.Nodes}, nil
+}
+
+func (e HeatmapEntry) Matches(class string, ascendancy string) bool {
+	return (class == "" || strings.EqualFold(e.Class, class)) &&
+		(ascendancy == "" || strings.EqualFold(e.Ascendancy, ascendancy))
+}
+
+func NewHeatmap(tree Tree, entries []HeatmapEntry, class string, ascendancy string) (*Heatmap, error) {
+	heatmap := &Heatmap{
+		Class:      class,
+		Ascendancy: ascendancy,
+		Counts:     make(map[int]int),
+	}
+	unknown := 0
+	for _, entry := range entries {
+		entry, err := entry.Resolve(tree)
+		if err != nil {
+			return nil, err
+		}
+		if !entry.Matches(class, ascendancy) {
+			if entry.Class == "" && entry.Ascendancy == "" {
+				unknown++
+			}
+			continue
+		}
+		heatmap.Builds++
+		seen := make(map[int]bool, len(entry.Nodes))
+		for _, hash := range entry.Nodes {
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			heatmap.Counts[hash]++
+			heatmap.max = max(heatmap.max, heatmap.Counts[hash])
+		}
+	}
+	// plain node lists have no class to filter by
+	if unknown > 0 {
+		log.Printf("warning: skipped %d node lists without a class or ascendancy to filter by", unknown)
+	}
+	return heatmap, nil
+}
+
+// returns the share of builds that allocated the node
+func (h *Heatmap) Frequency(hash int) float64 {
+	if h.Builds == 0 {
+		return 0
+	}
+	return float64(h.Counts[hash]) / float64(h.Builds)
+}
+
+// scales the frequency relative to the most allocated node so that
+// rarely allocated nodes remain distinguishable
+func (h *Heatmap) intensity(hash int) float64 {
+	if h.max == 0 {
+		return 0
+	}
+	return math.Sqrt(float64(h.Counts[hash]) / float64(h.max))
+}
+
+var heatmapColors = [][3]float64{
+	{0x30, 0x30, 0x60},
+	{0x20, 0x90, 0xc0},
+	{0x40, 0xc0, 0x40},
+	{0xf0, 0xd0, 0x20},
+	{0xe0, 0x30, 0x20},
+}
+
+func (h *Heatmap) Color(hash int) string {
+	t := h.intensity(hash) * float64(len(heatmapColors)-1)
+	i := min(int(t), len(heatmapColors)-2)
+	f := t - float64(i)
+	c := [3]int{}
+	for channel := range c {
+		c[channel] = int(heatmapColors[i][channel]*(1-f) + heatmapColors[i+1][channel]*f)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
+
+func (h *Heatmap) Radius(hash int, radius int) int {
+	return int(float64(radius) * (0.6 + 0.8*h.intensity(hash)))
+}
+
+func (h *Heatmap) Save(fileName string) error {
+	outFile, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	return json.NewEncoder(outFile).Encode(h)
+}
+
+func RunHeatmap(args []string) error {
+	fs, config := NewFlagSet("heatmap")
+	drawOptions := AddDrawFlags(fs)
+	builds := fs.String("builds", "", "JSON lines file with one build per line")
+	className := fs.String("class", "", "only count builds of this class")
+	// the allocated ascendancy of the draw flags doubles as the filter
+	ascendancy := fs.Lookup("ascendancy")
+	ascendancy.Usage = "only count builds of this ascendancy and highlight it"
+	svgOut := fs.String("svg", "", "file to write the heatmap SVG to (default heatmap.svg in the -out directory)")
+	countsOut := fs.String("counts", "", "file to write the per node counts to (default the SVG file with a .json extension)")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	if *builds == "" {
+		return fmt.Errorf("no builds given, use -builds")
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	options, err := drawOptions()
+	if err != nil {
+		return err
+	}
+	if options.Style == "" && options.StyleLink == "" {
+		options.Style = DefaultStyle
+	}
+	file, err := SelectTreeFile(cfg)
+	if err != nil {
+		return err
+	}
+	tree, err := LoadTree(file.Path)
+	if err != nil {
+		return err
+	}
+	entries, err := LoadHeatmapEntries(*builds)
+	if err != nil {
+		return err
+	}
+	heatmap, err := NewHeatmap(tree, entries, *className, ascendancy.Value.String())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Counted %d of %d builds\n", heatmap.Builds, len(entries))
+
+	if *svgOut == "" {
+		err = os.MkdirAll(cfg.OutputDir, os.ModePerm)
+		if err != nil {
+			return err
+		}
+		*svgOut = filepath.Join(cfg.OutputDir, "heatmap.svg")
+	}
+	outFile, err := os.Create(*svgOut)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	options.Heatmap = heatmap
+	WriteSvg(outFile, tree, options)
+
+	if *countsOut == "" {
+		*countsOut = strings.TrimSuffix(*svgOut, ".svg") + ".json"
+	}
+	return heatmap.Save(*countsOut)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"treegen/internal/shareurl"
+)
+
+func TestLoadHeatmapEntries(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "builds.jsonl")
+	data := `[101, 102]
+
+{"class": "Witch", "ascendancy": "Occultist", "nodes": [101, 201]}
+{"url": "https://www.pathofexile.com/passive-skill-tree/AAAABgEBAgBlAGYAAA=="}
+`
+	if err := os.WriteFile(fileName, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := LoadHeatmapEntries(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("loaded %d entries, want 3: %+v", len(entries), entries)
+	}
+	if !slices.Equal(entries[0].Nodes, []int{101, 102}) || entries[0].Class != "" {
+		t.Errorf("plain list = %+v", entries[0])
+	}
+	if entries[1].Class != "Witch" || entries[1].Ascendancy != "Occultist" || !slices.Equal(entries[1].Nodes, []int{101, 201}) {
+		t.Errorf("object = %+v", entries[1])
+	}
+	if !strings.HasPrefix(entries[2].Url, shareurl.Prefix) || entries[2].Nodes != nil {
+		t.Errorf("share url = %+v", entries[2])
+	}
+
+	if err := os.WriteFile(fileName, []byte("[101]\n{\"nodes\": [1,}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHeatmapEntries(fileName); err == nil || !strings.Contains(err.Error(), "builds.jsonl:2:") {
+		t.Errorf("got error %v, want one naming line 2", err)
+	}
+}
+
+func TestNewHeatmap(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := shareurl.Encode(shareurl.Build{ClassId: 1, AscendancyId: 2, Nodes: []int{101, 102, 301}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []HeatmapEntry{
+		// duplicate nodes of a build are counted once
+		{Nodes: []int{101, 101, 102}},
+		{Class: "Witch", Ascendancy: "Occultist", Nodes: []int{101, 201}},
+		{Url: url},
+		{Class: "Scion", Nodes: []int{101, 103}},
+	}
+	tests := []struct {
+		name              string
+		class, ascendancy string
+		builds            int
+		counts            map[int]int
+	}{
+		{"all", "", "", 4, map[int]int{101: 4, 102: 2, 103: 1, 201: 1, 301: 1}},
+		{"class", "witch", "", 2, map[int]int{101: 2, 102: 1, 201: 1, 301: 1}},
+		// share urls are resolved to their class and ascendancy
+		{"ascendancy", "", "Elementalist", 1, map[int]int{101: 1, 102: 1, 301: 1}},
+		{"class and ascendancy", "Witch", "Occultist", 1, map[int]int{101: 1, 201: 1}},
+		{"no match", "Templar", "", 0, map[int]int{}},
+	}
+	for _, test := range tests {
+		heatmap, err := NewHeatmap(tree, entries, test.class, test.ascendancy)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if heatmap.Builds != test.builds || len(heatmap.Counts) != len(test.counts) {
+			t.Errorf("%s: counted %d builds %v, want %d builds %v", test.name, heatmap.Builds, heatmap.Counts, test.builds, test.counts)
+			continue
+		}
+		for hash, count := range test.counts {
+			if heatmap.Counts[hash] != count {
+				t.Errorf("%s: node %d counted %d times, want %d", test.name, hash, heatmap.Counts[hash], count)
+			}
+		}
+	}
+
+	heatmap, err := NewHeatmap(tree, entries, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := heatmap.Frequency(102); f != 0.5 {
+		t.Errorf("Frequency(102) = %f, want 0.5", f)
+	}
+	// the most allocated node gets the hottest color and the largest radius
+	if c := heatmap.Color(101); c != "#e03020" {
+		t.Errorf("Color(101) = %s", c)
+	}
+	if c := heatmap.Color(104); c != "#303060" {
+		t.Errorf("Color(104) = %s", c)
+	}
+	if r := heatmap.Radius(101, 50); r != 70 {
+		t.Errorf("Radius(101) = %d, want 70", r)
+	}
+	if r := heatmap.Radius(104, 50); r != 30 {
+		t.Errorf("Radius(104) = %d, want 30", r)
+	}
+
+	if _, err := NewHeatmap(tree, []HeatmapEntry{{Url: shareurl.Prefix + "AAAA"}}, "", ""); err == nil {
+		t.Error("NewHeatmap accepted an invalid share url")
+	}
+}
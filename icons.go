@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+type IconOptions struct {
+	// directory the sprite sheets are referenced from, relative to the svg
+	AssetDir string
+	// index into the tree's image zoom levels, negative for the largest
+	ZoomLevel int
+}
+
+// returns the sprite type and icon path of a node, the export has separate
+// sprites for allocated and unallocated nodes
+func nodeIcon(node Node, active bool) (string, string) {
+	state := "Inactive"
+	if active {
+		state = "Active"
+	}
+	switch {
+	case node.IsMastery:
+		if active && node.ActiveIcon != nil {
+			return "masteryActiveSelected", *node.ActiveIcon
+		}
+		if node.InactiveIcon != nil {
+			return "masteryInactive", *node.InactiveIcon
+		}
+		if node.Icon != nil {
+			return "mastery", *node.Icon
+		}
+		return "", ""
+	case node.Icon == nil:
+		return "", ""
+	case node.IsKeystone:
+		return "keystone" + state, *node.Icon
+	case node.IsNotable:
+		return "notable" + state, *node.Icon
+	default:
+		return "normal" + state, *node.Icon
+	}
+}
+
+// returns the scale of the sprites at a zoom level relative to the tree
+// coordinates, 0 if the tree has no sprites
+func (t Tree) ImageZoom(zoomLevel int) float64 {
+	if len(t.ImageZoomLevels) == 0 {
+		return 0
+	}
+	if zoomLevel < 0 || zoomLevel >= len(t.ImageZoomLevels) {
+		zoomLevel = len(t.ImageZoomLevels) - 1
+	}
+	return t.ImageZoomLevels[zoomLevel]
+}
+
+// returns the sprite sheet of a sprite type at the given zoom level
+func (t Tree) SpriteSheet(spriteType string, zoomLevel int) (Sprite, bool) {
+	zoom := t.ImageZoom(zoomLevel)
+	if zoom == 0 {
+		return Sprite{}, false
+	}
+	// the keys are formatted with varying precision across versions
+	for key, sprite := range t.Sprites[spriteType] {
+		value, err := strconv.ParseFloat(key, 64)
+		if err == nil && math.Abs(value-zoom) < 1e-6 {
+			return sprite, true
+		}
+	}
+	return Sprite{}, false
+}
+
+// the sprite sheet file names are urls of the cdn, only the base name is kept
+func spriteFileName(fileName string) string {
+	if u, err := url.Parse(fileName); err == nil {
+		fileName = u.Path
+	}
+	return path.Base(fileName)
+}
+
+// older exports do not include the sheet size, it is derived from the coords
+func spriteSize(sprite Sprite) (int, int) {
+	w, h := sprite.W, sprite.H
+	for _, coords := range sprite.Coords {
+		w = max(w, coords.X+coords.W)
+		h = max(h, coords.Y+coords.H)
+	}
+	return w, h
+}
+
+// writes a pattern for every icon used by the tree and remembers which
+// pattern fills which node, the node circles clip the patterns
+func (d *TreeDrawer) DrawIconPatterns() {
+	d.icons = make(map[int]string)
+	patterns := make(map[string]string)
+	d.s.Def()
+	for _, nodeid := range SortedNodeIds(d.Tree) {
+		node := d.Tree.Nodes[nodeid]
+		if !node.ShouldDraw() {
+			continue
+		}
+		spriteType, icon := nodeIcon(node, d.IsAllocated(node))
+		if icon == "" {
+			continue
+		}
+		key := spriteType + "/" + icon
+		if id, exists := patterns[key]; exists {
+			d.icons[node.Skill] = id
+			continue
+		}
+		sprite, exists := d.Tree.SpriteSheet(spriteType, d.Options.Icons.ZoomLevel)
+		if !exists {
+			continue
+		}
+		coords, exists := sprite.Coords[icon]
+		if !exists {
+			continue
+		}
+		id := fmt.Sprintf("icon-%d", len(patterns))
+		patterns[key] = id
+		d.icons[node.Skill] = id
+
+		d.SpritePattern(id, sprite, coords, d.Options.Icons.AssetDir, "xMidYMid slice")
+	}
+	d.s.DefEnd()
+}
+
+// writes a pattern showing a single sprite, scaled to the bounding box of
+// the shape it fills
+func (d *TreeDrawer) SpritePattern(id string, sprite Sprite, coords SpriteCoords, assetDir string, aspectRatio string) {
+	w, h := spriteSize(sprite)
+	href := html.EscapeString(path.Join(assetDir, spriteFileName(sprite.FileName)))
+	d.s.Pattern(id, 0, 0, 1, 1, "bbox", fmt.Sprintf("viewBox=\"%d %d %d %d\" preserveAspectRatio=\"%s\"", coords.X, coords.Y, coords.W, coords.H, aspectRatio))
+	d.s.Image(0, 0, w, h, href)
+	d.s.PatternEnd()
+}
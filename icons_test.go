@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDrawIconPatterns(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	icon := "Art/2DArt/SkillIcons/passives/ArcaneFocus.png"
+	for _, nodeid := range []string{"102", "201"} {
+		node := tree.Nodes[nodeid]
+		node.Icon = &icon
+		tree.Nodes[nodeid] = node
+	}
+	sprite := Sprite{
+		FileName: "https://web.poecdn.com/image/passive-skill/skills-3.jpg?1a2b",
+		Coords:   map[string]SpriteCoords{icon: {X: 10, Y: 20, W: 30, H: 30}},
+	}
+	tree.Sprites = map[string]map[string]Sprite{"notableInactive": {"0.3835": sprite}}
+
+	svg := renderSvg(t, tree, DrawOptions{Icons: &IconOptions{AssetDir: "assets", ZoomLevel: -1}})
+	pattern := `<pattern id="icon-0" x="0" y="0" width="1" height="1" patternUnits="objectBoundingBox" viewBox="10 20 30 30" preserveAspectRatio="xMidYMid slice" >`
+	if !strings.Contains(svg, pattern) {
+		t.Errorf("no pattern for the icon in\n%s", svg)
+	}
+	// the sheet size is derived from the coords and the cdn url is reduced to the file name
+	if !strings.Contains(svg, `<image x="0" y="0" width="40" height="50" xlink:href="assets/skills-3.jpg" />`) {
+		t.Errorf("the pattern does not show the sprite sheet in\n%s", svg)
+	}
+	if count := strings.Count(svg, "<pattern "); count != 1 {
+		t.Errorf("wrote %d patterns for one icon", count)
+	}
+	// nodes sharing an icon share the pattern
+	for _, id := range []string{"n-102", "n-201"} {
+		if element := svgElement(svg, id); !strings.Contains(element, `style="fill:url(#icon-0)"`) {
+			t.Errorf("%s is not filled with the icon: %s", id, element)
+		}
+	}
+	if element := svgElement(svg, "n-101"); strings.Contains(element, "style=") {
+		t.Errorf("a node without an icon is filled: %s", element)
+	}
+
+	// allocated nodes use the active sprites, which this tree does not have
+	svg = renderSvg(t, tree, DrawOptions{Icons: &IconOptions{AssetDir: "assets"}, Allocation: &Allocation{Nodes: []int{102}}})
+	if element := svgElement(svg, "n-102"); strings.Contains(element, "fill:url") {
+		t.Errorf("an allocated node is filled with the inactive icon: %s", element)
+	}
+}
@@ -0,0 +1,208 @@
+// Package shareurl encodes and decodes the allocations of the official
+// passive tree share urls, checking them against a tree is up to the caller.
+package shareurl
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the official passive tree share urls encode the allocation as url safe
+// base64 of the following big endian layout:
+//
+//	version 4: version(4) class(1) ascendancy(1) fullscreen(1) nodes(2 each)
+//	version 5: version(4) class(1) ascendancy(1) nodeCount(1) nodes(2 each)
+//	           clusterCount(1) clusterNodes(2 each)
+//	version 6: version 5 followed by masteryCount(1) masteries(effect(2) node(2) each)
+//
+// the ascendancy byte holds the ascendancy in its lower two bits and the
+// alternate ascendancy in the next two bits
+const Prefix = "https://www.pathofexile.com/passive-skill-tree/"
+
+const (
+	MinVersion        = 4
+	MaxVersion        = 6
+	clusterNodeOffset = 65536
+)
+
+type Build struct {
+	Version               int
+	ClassId               int
+	AscendancyId          int
+	AlternateAscendancyId int
+	Nodes                 []int
+	ClusterNodes          []int
+	// maps mastery node hashes to the chosen effect
+	MasteryEffects map[int]int
+}
+
+func Decode(url string) (Build, error) {
+	build := Build{MasteryEffects: make(map[int]int)}
+	code := url
+	if i := strings.IndexAny(code, "?#"); i >= 0 {
+		code = code[:i]
+	}
+	code = strings.TrimRight(code, "/")
+	code = code[strings.LastIndex(code, "/")+1:]
+	code = strings.NewReplacer("+", "-", "/", "_").Replace(strings.TrimRight(code, "="))
+	data, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil {
+		return build, fmt.Errorf("invalid tree link: %w", err)
+	}
+	if len(data) < 7 {
+		return build, fmt.Errorf("invalid tree link: too short")
+	}
+	build.Version = int(binary.BigEndian.Uint32(data[0:4]))
+	if build.Version < MinVersion || build.Version > MaxVersion {
+		return build, fmt.Errorf("invalid tree link: unsupported version %d", build.Version)
+	}
+	build.ClassId = int(data[4])
+	build.AscendancyId = int(data[5] & 3)
+	build.AlternateAscendancyId = int(data[5]>>2) & 3
+
+	if build.Version == 4 {
+		if (len(data)-7)%2 != 0 {
+			return build, fmt.Errorf("invalid tree link: truncated node list")
+		}
+		build.Nodes, err = readNodeHashes(data[7:], (len(data)-7)/2)
+		return build, err
+	}
+
+	offset := 6
+	readCount := func() (int, error) {
+		if offset >= len(data) {
+			return 0, fmt.Errorf("invalid tree link: unexpected end of data")
+		}
+		count := int(data[offset])
+		offset++
+		return count, nil
+	}
+	nodeCount, err := readCount()
+	if err != nil {
+		return build, err
+	}
+	build.Nodes, err = readNodeHashes(data[offset:], nodeCount)
+	if err != nil {
+		return build, err
+	}
+	offset += nodeCount * 2
+
+	clusterCount, err := readCount()
+	if err != nil {
+		return build, err
+	}
+	build.ClusterNodes, err = readNodeHashes(data[offset:], clusterCount)
+	if err != nil {
+		return build, err
+	}
+	for i := range build.ClusterNodes {
+		build.ClusterNodes[i] += clusterNodeOffset
+	}
+	offset += clusterCount * 2
+	if build.Version < 6 {
+		return build, nil
+	}
+
+	masteryCount, err := readCount()
+	if err != nil {
+		return build, err
+	}
+	masteries, err := readNodeHashes(data[offset:], masteryCount*2)
+	if err != nil {
+		return build, err
+	}
+	for i := 0; i < len(masteries); i += 2 {
+		build.MasteryEffects[masteries[i+1]] = masteries[i]
+	}
+	return build, nil
+}
+
+func readNodeHashes(data []byte, count int) ([]int, error) {
+	if len(data) < count*2 {
+		return nil, fmt.Errorf("invalid tree link: unexpected end of data")
+	}
+	nodes := make([]int, count)
+	for i := range nodes {
+		nodes[i] = int(binary.BigEndian.Uint16(data[i*2:]))
+	}
+	return nodes, nil
+}
+
+func Encode(build Build) (string, error) {
+	version := build.Version
+	if version == 0 {
+		version = MaxVersion
+	}
+	if version < MinVersion || version > MaxVersion {
+		return "", fmt.Errorf("unsupported tree link version %d", version)
+	}
+	if build.ClassId < 0 || build.ClassId > 0xff {
+		return "", fmt.Errorf("class id %d out of range", build.ClassId)
+	}
+	if build.AscendancyId < 0 || build.AscendancyId > 3 || build.AlternateAscendancyId < 0 || build.AlternateAscendancyId > 3 {
+		return "", fmt.Errorf("ascendancy id out of range")
+	}
+	// older versions have no room for them, dropping them would change the build
+	if version < 5 && len(build.ClusterNodes) > 0 {
+		return "", fmt.Errorf("tree link version %d cannot hold cluster nodes", version)
+	}
+	if version < 6 && len(build.MasteryEffects) > 0 {
+		return "", fmt.Errorf("tree link version %d cannot hold mastery effects", version)
+	}
+	nodes := append([]int{}, build.Nodes...)
+	sort.Ints(nodes)
+	if version > 4 && len(nodes) > 255 {
+		return "", fmt.Errorf("too many allocated nodes: %d", len(nodes))
+	}
+
+	data := binary.BigEndian.AppendUint32(nil, uint32(version))
+	data = append(data, byte(build.ClassId), byte(build.AscendancyId|build.AlternateAscendancyId<<2))
+	if version == 4 {
+		data = append(data, 0)
+	} else {
+		data = append(data, byte(len(nodes)))
+	}
+	for _, node := range nodes {
+		if node < 0 || node > 0xffff {
+			return "", fmt.Errorf("node hash %d out of range", node)
+		}
+		data = binary.BigEndian.AppendUint16(data, uint16(node))
+	}
+	if version > 4 {
+		clusterNodes := append([]int{}, build.ClusterNodes...)
+		sort.Ints(clusterNodes)
+		if len(clusterNodes) > 255 {
+			return "", fmt.Errorf("too many allocated cluster nodes: %d", len(clusterNodes))
+		}
+		data = append(data, byte(len(clusterNodes)))
+		for _, node := range clusterNodes {
+			// cluster jewel nodes are stored relative to the offset
+			if node < clusterNodeOffset || node-clusterNodeOffset > 0xffff {
+				return "", fmt.Errorf("cluster node hash %d out of range", node)
+			}
+			data = binary.BigEndian.AppendUint16(data, uint16(node-clusterNodeOffset))
+		}
+	}
+	if version > 5 {
+		masteries := make([]int, 0, len(build.MasteryEffects))
+		for node := range build.MasteryEffects {
+			masteries = append(masteries, node)
+		}
+		sort.Ints(masteries)
+		if len(masteries) > 255 {
+			return "", fmt.Errorf("too many mastery effects: %d", len(masteries))
+		}
+		data = append(data, byte(len(masteries)))
+		for _, node := range masteries {
+			if node < 0 || node > 0xffff || build.MasteryEffects[node] < 0 || build.MasteryEffects[node] > 0xffff {
+				return "", fmt.Errorf("mastery %d or its effect %d out of range", node, build.MasteryEffects[node])
+			}
+			data = binary.BigEndian.AppendUint16(data, uint16(build.MasteryEffects[node]))
+			data = binary.BigEndian.AppendUint16(data, uint16(node))
+		}
+	}
+	return Prefix + base64.URLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,141 @@
+package shareurl
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func sameBuild(a, b Build) bool {
+	return a.Version == b.Version && a.ClassId == b.ClassId && a.AscendancyId == b.AscendancyId &&
+		a.AlternateAscendancyId == b.AlternateAscendancyId && slices.Equal(a.Nodes, b.Nodes) &&
+		slices.Equal(a.ClusterNodes, b.ClusterNodes) && maps.Equal(a.MasteryEffects, b.MasteryEffects)
+}
+
+var shareURLTests = []struct {
+	name  string
+	url   string
+	build Build
+}{
+	{
+		name:  "empty scion",
+		url:   "https://www.pathofexile.com/passive-skill-tree/AAAABgAAAAAA",
+		build: Build{Version: 6, Nodes: []int{}, ClusterNodes: []int{}, MasteryEffects: map[int]int{}},
+	},
+	{
+		name:  "empty witch",
+		url:   "https://www.pathofexile.com/passive-skill-tree/AAAABgMAAAAA",
+		build: Build{Version: 6, ClassId: 3, Nodes: []int{}, ClusterNodes: []int{}, MasteryEffects: map[int]int{}},
+	},
+	{
+		name: "version 6 with cluster nodes and masteries",
+		url:  "https://www.pathofexile.com/passive-skill-tree/AAAABgMGAwBlAGYAZwIABwEsAb0BAG4=",
+		build: Build{
+			Version: 6, ClassId: 3, AscendancyId: 2, AlternateAscendancyId: 1,
+			Nodes:          []int{101, 102, 103},
+			ClusterNodes:   []int{65543, 65836},
+			MasteryEffects: map[int]int{110: 48385},
+		},
+	},
+	{
+		name:  "version 5",
+		url:   "https://www.pathofexile.com/passive-skill-tree/AAAABQEBAgBlAGYBAAc=",
+		build: Build{Version: 5, ClassId: 1, AscendancyId: 1, Nodes: []int{101, 102}, ClusterNodes: []int{65543}, MasteryEffects: map[int]int{}},
+	},
+	{
+		name:  "version 4",
+		url:   "https://www.pathofexile.com/passive-skill-tree/AAAABAEBAABlAGY=",
+		build: Build{Version: 4, ClassId: 1, AscendancyId: 1, Nodes: []int{101, 102}, MasteryEffects: map[int]int{}},
+	},
+}
+
+func TestDecode(t *testing.T) {
+	for _, test := range shareURLTests {
+		build, err := Decode(test.url)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !sameBuild(build, test.build) {
+			t.Errorf("%s: decoded %+v, want %+v", test.name, build, test.build)
+		}
+	}
+}
+
+func TestDecodeVariants(t *testing.T) {
+	want := shareURLTests[1].build
+	for _, url := range []string{
+		"AAAABgMAAAAA",
+		"https://www.pathofexile.com/fullscreen-passive-skill-tree/3.25.0/AAAABgMAAAAA",
+		"https://www.pathofexile.com/passive-skill-tree/3.25.0/AAAABgMAAAAA/?accountName=someone#top",
+		"https://www.pathofexile.com/passive-skill-tree/AAAABgMAAAAA==",
+	} {
+		build, err := Decode(url)
+		if err != nil || !sameBuild(build, want) {
+			t.Errorf("Decode(%q) = %+v, %v", url, build, err)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, test := range shareURLTests {
+		url, err := Encode(test.build)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if url != test.url {
+			t.Errorf("%s: encoded %s, want %s", test.name, url, test.url)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  string
+	}{
+		{"odd version 4 node list", "AAAABAEBAABlAGYB", "truncated node list"},
+		{"unsupported version", "AAAABwAAAAAA", "unsupported version 7"},
+		{"truncated node list", "AAAABQEAAwBlAGY=", "unexpected end of data"},
+		{"too short", "AAAABgM", "too short"},
+		{"invalid base64", "AAAA*gMAAAAA", "invalid tree link"},
+	}
+	for _, test := range tests {
+		_, err := Decode(Prefix + test.code)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build Build
+	}{
+		{"node above 65535", Build{Nodes: []int{65536}}},
+		{"negative node", Build{Nodes: []int{-1}}},
+		{"cluster node below the offset", Build{ClusterNodes: []int{7}}},
+		{"cluster node above the offset range", Build{ClusterNodes: []int{131072}}},
+		{"mastery effect above 65535", Build{MasteryEffects: map[int]int{110: 70000}}},
+		{"unknown class", Build{ClassId: -1}},
+		{"ascendancy out of range", Build{AscendancyId: 4}},
+		{"unsupported version", Build{Version: 3}},
+		{"version 4 cluster nodes", Build{Version: 4, ClusterNodes: []int{65543}}},
+		{"version 5 mastery effects", Build{Version: 5, MasteryEffects: map[int]int{110: 48385}}},
+	}
+	for _, test := range tests {
+		url, err := Encode(test.build)
+		if err == nil {
+			t.Errorf("%s: encoded %s, want an error", test.name, url)
+		}
+	}
+	// the largest cluster node still fits
+	_, err := Encode(Build{ClusterNodes: []int{131071}})
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+)
+
+type LabelOptions struct {
+	FontSize int
+}
+
+type labelBox struct {
+	minX, minY, maxX, maxY float64
+	// ascendancy trees are drawn on top of each other and shown one at a
+	// time, so boxes of different ascendancies never collide
+	ascendancy string
+}
+
+func (b labelBox) overlap(o labelBox) float64 {
+	if b.ascendancy != "" && o.ascendancy != "" && b.ascendancy != o.ascendancy {
+		return 0
+	}
+	w := math.Min(b.maxX, o.maxX) - math.Max(b.minX, o.minX)
+	h := math.Min(b.maxY, o.maxY) - math.Max(b.minY, o.minY)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
+
+type label struct {
+	node     Node
+	x, y     int
+	radius   int
+	priority int
+}
+
+// the directions a label may be placed in around its node, in order of
+// preference
+var labelDirections = [][2]float64{
+	{0, 1}, {0, -1}, {1, 0}, {-1, 0},
+	{1, 1}, {-1, 1}, {1, -1}, {-1, -1},
+}
+
+// returns the bounding box of a label placed next to a node, text widths are
+// estimated since the font is chosen by the viewer
+func (o LabelOptions) box(l label, name string, direction [2]float64) labelBox {
+	width := float64(len([]rune(name))) * float64(o.FontSize) * 0.55
+	height := float64(o.FontSize)
+	gap := float64(l.radius) + float64(o.FontSize)/4
+	cx := float64(l.x) + direction[0]*(gap+width/2)
+	cy := float64(l.y) + direction[1]*(gap+height/2)
+	return labelBox{cx - width/2, cy - height/2, cx + width/2, cy + height/2, nodeAscendancy(l.node)}
+}
+
+func nodeAscendancy(node Node) string {
+	if node.AscendancyName == nil {
+		return ""
+	}
+	return *node.AscendancyName
+}
+
+// draws the names of keystones and notables, placing each label on the side
+// of its node that overlaps the fewest nodes and already placed labels
+func (d *TreeDrawer) DrawLabels() {
+	options := *d.Options.Labels
+	obstacles := make([]labelBox, 0)
+	labels := make([]label, 0)
+	for _, nodeid := range SortedNodeIds(d.Tree) {
+		node := d.Tree.Nodes[nodeid]
+		if !node.ShouldDraw() {
+			continue
+		}
+		x, y, err := d.GetCoordinates(node)
+		if err != nil {
+			continue
+		}
+		radius := NodeRadius(node)
+		obstacles = append(obstacles, labelBox{float64(x - radius), float64(y - radius), float64(x + radius), float64(y + radius), nodeAscendancy(node)})
+		if node.Name == nil || !(node.IsKeystone || node.IsNotable) {
+			continue
+		}
+		priority := 1
+		if node.IsKeystone {
+			priority = 0
+		}
+		labels = append(labels, label{node: node, x: x, y: y, radius: radius, priority: priority})
+	}
+	// keystones get the best positions
+	sort.SliceStable(labels, func(i, j int) bool {
+		return labels[i].priority < labels[j].priority
+	})
+
+	d.s.Gid("labels")
+	for _, l := range labels {
+		name := *l.node.Name
+		best, bestOverlap := labelBox{}, math.Inf(1)
+		for _, direction := range labelDirections {
+			box := options.box(l, name, direction)
+			overlap := 0.0
+			for _, obstacle := range obstacles {
+				overlap += box.overlap(obstacle)
+			}
+			if overlap < bestOverlap {
+				best, bestOverlap = box, overlap
+			}
+			if overlap == 0 {
+				break
+			}
+		}
+		obstacles = append(obstacles, best)
+
+		classes := "label notable"
+		if l.node.IsKeystone {
+			classes = "label keystone"
+		}
+		attr := fmt.Sprintf("id=\"l-%d\" font-size=\"%d\" text-anchor=\"middle\" dominant-baseline=\"central\"", l.node.Skill, options.FontSize)
+		if l.node.AscendancyName != nil {
+			classes += " ascendancy"
+			attr += fmt.Sprintf(" data-extras=\"%s\"", html.EscapeString(*l.node.AscendancyName))
+		}
+		attr += fmt.Sprintf(" class=\"%s\"", classes)
+		d.s.Text(int((best.minX+best.maxX)/2), int((best.minY+best.maxY)/2), name, attr)
+	}
+	d.s.Gend()
+}
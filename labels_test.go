@@ -0,0 +1,93 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDrawLabels(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := renderSvg(t, tree, DrawOptions{Labels: &LabelOptions{FontSize: 40}})
+	start := strings.Index(svg, `<g id="labels">`)
+	if start < 0 {
+		t.Fatalf("no labels group in\n%s", svg)
+	}
+	group := svg[start : start+strings.Index(svg[start:], "</g>")]
+	tests := []struct {
+		id      string
+		classes []string
+		name    string
+	}{
+		{"l-102", []string{"label", "notable"}, "Arcane Focus"},
+		{"l-104", []string{"label", "keystone"}, "Elemental Equilibrium"},
+		{"l-201", []string{"label", "notable", "ascendancy"}, "Vile Bastion"},
+	}
+	for _, test := range tests {
+		element := svgElement(group, test.id)
+		if classes := svgClasses(element); !slices.Equal(classes, test.classes) {
+			t.Errorf("%s has classes %v, want %v", test.id, classes, test.classes)
+		}
+		if !strings.Contains(group, element+test.name+"</text>") {
+			t.Errorf("%s does not read %q", test.id, test.name)
+		}
+	}
+	// small passives and masteries are not labelled
+	for _, id := range []string{"l-101", "l-103", "l-110"} {
+		if element := svgElement(svg, id); element != "" {
+			t.Errorf("labelled %s: %s", id, element)
+		}
+	}
+	if strings.Contains(renderSvg(t, tree, DrawOptions{}), `id="labels"`) {
+		t.Error("drew labels without the option")
+	}
+}
+
+func TestDrawLabelsPlacement(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second notable right next to arcane focus
+	neighbour := tree.Nodes["103"]
+	neighbour.IsNotable = true
+	neighbour.OrbitIndex = 5
+	tree.Nodes["103"] = neighbour
+	options := LabelOptions{FontSize: 40}
+	svg := renderSvg(t, tree, DrawOptions{Labels: &options})
+
+	boxes := make(map[string]labelBox)
+	for _, id := range []string{"102", "103"} {
+		match := regexp.MustCompile(`<text x="(-?\d+)" y="(-?\d+)" id="l-` + id + `"[^>]*>([^<]*)</text>`).FindStringSubmatch(svg)
+		if match == nil {
+			t.Fatalf("no label for %s", id)
+		}
+		x, _ := strconv.ParseFloat(match[1], 64)
+		y, _ := strconv.ParseFloat(match[2], 64)
+		width := float64(len([]rune(match[3]))) * float64(options.FontSize) * 0.55
+		height := float64(options.FontSize)
+		boxes[id] = labelBox{x - width/2, y - height/2, x + width/2, y + height/2, ""}
+	}
+	if overlap := boxes["102"].overlap(boxes["103"]); overlap > 0 {
+		t.Errorf("the labels overlap by %f: %+v", overlap, boxes)
+	}
+	// neither label covers either node
+	drawer := NewTreeDrawer(nil, tree, DrawOptions{})
+	for _, nodeid := range []string{"102", "103"} {
+		x, y, err := drawer.GetCoordinates(tree.Nodes[nodeid])
+		if err != nil {
+			t.Fatal(err)
+		}
+		node := labelBox{float64(x - NotableRadius), float64(y - NotableRadius), float64(x + NotableRadius), float64(y + NotableRadius), ""}
+		for id, box := range boxes {
+			if overlap := box.overlap(node); overlap > 0 {
+				t.Errorf("the label of %s covers node %s by %f", id, nodeid, overlap)
+			}
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// exports before 3.10 were published as data.txt or data files, either as
+// plain json or wrapped in javascript like "var passiveSkillTreeData = {...};",
+// and use an older schema with abbreviated node fields
+
+type legacyId string
+
+func (id *legacyId) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	err := json.Unmarshal(data, &number)
+	if err == nil {
+		*id = legacyId(number.String())
+		return nil
+	}
+	var s string
+	err = json.Unmarshal(data, &s)
+	*id = legacyId(s)
+	return err
+}
+
+func legacyIds(ids []legacyId) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = string(id)
+	}
+	return result
+}
+
+type legacyNode struct {
+	Id                     legacyId        `json:"id"`
+	Name                   string          `json:"dn"`
+	Icon                   string          `json:"icon"`
+	IsKeystone             bool            `json:"ks"`
+	IsNotable              bool            `json:"not"`
+	IsMastery              bool            `json:"m"`
+	IsJewelSocket          bool            `json:"isJewelSocket"`
+	IsMultipleChoice       bool            `json:"isMultipleChoice"`
+	IsMultipleChoiceOption bool            `json:"isMultipleChoiceOption"`
+	IsAscendancyStart      bool            `json:"isAscendancyStart"`
+	IsBlighted             bool            `json:"isBlighted"`
+	IsProxy                bool            `json:"isProxy"`
+	AscendancyName         string          `json:"ascendancyName"`
+	GrantedPassivePoints   int             `json:"passivePointsGranted"`
+	ClassStarts            []int           `json:"spc"`
+	Stats                  []string        `json:"sd"`
+	ReminderText           []string        `json:"reminderText"`
+	FlavourText            []string        `json:"flavourText"`
+	ExpansionJewel         *ExpansionJewel `json:"expansionJewel"`
+	Group                  int             `json:"g"`
+	Orbit                  int             `json:"o"`
+	OrbitIndex             int             `json:"oidx"`
+	GrantedStrength        int             `json:"sa"`
+	GrantedDexterity       int             `json:"da"`
+	GrantedIntelligence    int             `json:"ia"`
+	Out                    []legacyId      `json:"out"`
+	In                     []legacyId      `json:"in"`
+}
+
+type legacyGroup struct {
+	X       float64         `json:"x"`
+	Y       float64         `json:"y"`
+	Orbits  json.RawMessage `json:"oo"`
+	Nodes   []legacyId      `json:"n"`
+	IsProxy bool            `json:"isProxy"`
+}
+
+type legacySprite struct {
+	FileName string                  `json:"filename"`
+	Coords   map[string]SpriteCoords `json:"coords"`
+}
+
+type legacyTree struct {
+	CharacterData   map[string]Classes        `json:"characterData"`
+	Groups          map[string]legacyGroup    `json:"groups"`
+	Root            *legacyNode               `json:"root"`
+	Nodes           json.RawMessage           `json:"nodes"`
+	ExtraImages     map[string]ExtraImage     `json:"extraImages"`
+	MinX            int                       `json:"min_x"`
+	MinY            int                       `json:"min_y"`
+	MaxX            int                       `json:"max_x"`
+	MaxY            int                       `json:"max_y"`
+	Constants       Constants                 `json:"constants"`
+	SkillSprites    map[string][]legacySprite `json:"skillSprites"`
+	ImageZoomLevels []float64                 `json:"imageZoomLevels"`
+	Points          *PassivePoints            `json:"points"`
+}
+
+// the class and ascendancy names are not part of the legacy exports, their
+// order matches the class indices used by the nodes
+var legacyClasses = []struct {
+	Name         string
+	Ascendancies []string
+}{
+	{"Scion", []string{"Ascendant"}},
+	{"Marauder", []string{"Juggernaut", "Berserker", "Chieftain"}},
+	{"Ranger", []string{"Raider", "Deadeye", "Pathfinder"}},
+	{"Witch", []string{"Occultist", "Elementalist", "Necromancer"}},
+	{"Duelist", []string{"Slayer", "Gladiator", "Champion"}},
+	{"Templar", []string{"Inquisitor", "Hierophant", "Guardian"}},
+	{"Shadow", []string{"Assassin", "Trickster", "Saboteur"}},
+}
+
+// the fallback totals for legacy exports without points, those of the 3.x
+// trees: 99 points from levels and 24 from quests plus 8 ascendancy points.
+// Older trees may have granted other totals, their allocations are checked
+// against these as well.
+var legacyPoints = PassivePoints{TotalPoints: 123, AscendancyPoints: 8}
+
+type exportSchema int
+
+const (
+	currentExport exportSchema = iota
+	legacyExport
+	poe2Export
+)
+
+// exportProbe holds the fields that tell the export schemas apart
+type exportProbe struct {
+	CharacterData json.RawMessage `json:"characterData"`
+	Tree          json.RawMessage `json:"tree"`
+	Nodes         nodesProbe      `json:"nodes"`
+}
+
+// nodesProbe records whether the nodes list their connections, which path of
+// exile 2 exports do instead of listing out and in
+type nodesProbe struct {
+	hasConnections bool
+}
+
+func (p *nodesProbe) UnmarshalJSON(data []byte) error {
+	// the oldest exports list the nodes instead of mapping them by id
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+	var nodes map[string]struct {
+		Connections json.RawMessage `json:"connections"`
+	}
+	err := json.Unmarshal(data, &nodes)
+	for _, node := range nodes {
+		if node.Connections != nil {
+			p.hasConnections = true
+			break
+		}
+	}
+	return err
+}
+
+// probeExport returns the json object of an export, without the javascript
+// wrapper of some legacy exports, and its schema
+func probeExport(data []byte) ([]byte, exportSchema, error) {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, currentExport, fmt.Errorf("no json object found")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data[start:]))
+	probe := exportProbe{}
+	err := decoder.Decode(&probe)
+	if err != nil {
+		return nil, currentExport, err
+	}
+	object := data[start : start+int(decoder.InputOffset())]
+	if probe.CharacterData != nil && probe.Tree == nil {
+		return object, legacyExport, nil
+	}
+	if probe.Nodes.hasConnections {
+		return object, poe2Export, nil
+	}
+	return object, currentExport, nil
+}
+
+// DecodeTree decodes current, legacy and path of exile 2 exports into a Tree
+func DecodeTree(data []byte) (Tree, error) {
+	object, schema, err := probeExport(data)
+	if err != nil {
+		return Tree{}, err
+	}
+	return decodeExport(object, schema)
+}
+
+func decodeExport(object []byte, schema exportSchema) (Tree, error) {
+	switch schema {
+	case legacyExport:
+		return DecodeLegacyTree(object)
+	case poe2Export:
+		return DecodePoE2Tree(object)
+	}
+	tree := Tree{}
+	err := json.Unmarshal(object, &tree)
+	return tree, err
+}
+
+func DecodeLegacyTree(data []byte) (Tree, error) {
+	legacy := legacyTree{}
+	err := json.Unmarshal(data, &legacy)
+	if err != nil {
+		return Tree{}, err
+	}
+	tree := Tree{
+		Tree:            "Default",
+		Groups:          make(map[string]Group, len(legacy.Groups)),
+		Nodes:           make(map[string]Node),
+		ExtraImages:     legacy.ExtraImages,
+		MinX:            legacy.MinX,
+		MinY:            legacy.MinY,
+		MaxX:            legacy.MaxX,
+		MaxY:            legacy.MaxY,
+		Constants:       legacy.Constants,
+		ImageZoomLevels: legacy.ImageZoomLevels,
+		Points:          legacyPoints,
+	}
+	if legacy.Points != nil {
+		tree.Points = *legacy.Points
+	}
+
+	for i, class := range legacyClasses {
+		attributes := legacy.CharacterData[strconv.Itoa(i)]
+		converted := Classes{
+			Name:    class.Name,
+			BaseStr: attributes.BaseStr,
+			BaseDex: attributes.BaseDex,
+			BaseInt: attributes.BaseInt,
+		}
+		for _, ascendancy := range class.Ascendancies {
+			converted.Ascendancies = append(converted.Ascendancies, Ascendancy{Id: ascendancy, Name: ascendancy})
+		}
+		tree.Classes = append(tree.Classes, converted)
+	}
+
+	for groupId, group := range legacy.Groups {
+		orbits, err := legacyOrbits(group.Orbits)
+		if err != nil {
+			return Tree{}, fmt.Errorf("group %s: %w", groupId, err)
+		}
+		tree.Groups[groupId] = Group{
+			X:       group.X,
+			Y:       group.Y,
+			Orbits:  orbits,
+			Nodes:   legacyIds(group.Nodes),
+			IsProxy: group.IsProxy,
+		}
+	}
+
+	nodes, err := legacyNodes(legacy.Nodes)
+	if err != nil {
+		return Tree{}, err
+	}
+	for _, node := range nodes {
+		tree.Nodes[string(node.Id)] = node.Convert()
+	}
+	if legacy.Root != nil {
+		tree.Nodes["root"] = Node{Out: legacyIds(legacy.Root.Out), In: legacyIds(legacy.Root.In)}
+	}
+	fillIncomingConnections(&tree)
+
+	tree.Sprites = make(map[string]map[string]Sprite, len(legacy.SkillSprites))
+	for spriteType, sprites := range legacy.SkillSprites {
+		tree.Sprites[spriteType] = make(map[string]Sprite, len(sprites))
+		for i, sprite := range sprites {
+			if i >= len(legacy.ImageZoomLevels) {
+				break
+			}
+			zoom := strconv.FormatFloat(legacy.ImageZoomLevels[i], 'f', -1, 64)
+			tree.Sprites[spriteType][zoom] = Sprite{FileName: sprite.FileName, Coords: sprite.Coords}
+		}
+	}
+	return tree, nil
+}
+
+func (n legacyNode) Convert() Node {
+	node := Node{
+		Group:                  n.Group,
+		Orbit:                  n.Orbit,
+		OrbitIndex:             n.OrbitIndex,
+		Out:                    legacyIds(n.Out),
+		In:                     legacyIds(n.In),
+		Stats:                  n.Stats,
+		ReminderText:           n.ReminderText,
+		FlavourText:            n.FlavourText,
+		ExpansionJewel:         n.ExpansionJewel,
+		GrantedPassivePoints:   n.GrantedPassivePoints,
+		GrantedStrength:        n.GrantedStrength,
+		GrantedDexterity:       n.GrantedDexterity,
+		GrantedIntelligence:    n.GrantedIntelligence,
+		IsNotable:              n.IsNotable,
+		IsKeystone:             n.IsKeystone,
+		IsMastery:              n.IsMastery,
+		IsJewelSocket:          n.IsJewelSocket,
+		IsMultipleChoice:       n.IsMultipleChoice,
+		IsMultipleChoiceOption: n.IsMultipleChoiceOption,
+		IsAscendancyStart:      n.IsAscendancyStart,
+		IsBlighted:             n.IsBlighted,
+		IsProxy:                n.IsProxy,
+	}
+	node.Skill, _ = strconv.Atoi(string(n.Id))
+	if n.Name != "" {
+		name := n.Name
+		node.Name = &name
+	}
+	if n.Icon != "" {
+		icon := n.Icon
+		node.Icon = &icon
+	}
+	if n.AscendancyName != "" {
+		ascendancyName := n.AscendancyName
+		node.AscendancyName = &ascendancyName
+	}
+	if len(n.ClassStarts) > 0 {
+		classStartIndex := n.ClassStarts[0]
+		node.ClassStartIndex = &classStartIndex
+	}
+	return node
+}
+
+// nodes are a map keyed by id in later legacy exports and a list before that
+func legacyNodes(data json.RawMessage) ([]legacyNode, error) {
+	nodes := make([]legacyNode, 0)
+	if len(data) == 0 {
+		return nodes, nil
+	}
+	if data[0] == '[' {
+		err := json.Unmarshal(data, &nodes)
+		return nodes, err
+	}
+	nodeMap := make(map[string]legacyNode)
+	err := json.Unmarshal(data, &nodeMap)
+	if err != nil {
+		return nil, err
+	}
+	for id, node := range nodeMap {
+		if node.Id == "" {
+			node.Id = legacyId(id)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// orbits are either a map of orbit index to true or a list of booleans
+func legacyOrbits(data json.RawMessage) ([]int, error) {
+	orbits := make([]int, 0)
+	if len(data) == 0 {
+		return orbits, nil
+	}
+	if data[0] == '[' {
+		var flags []bool
+		err := json.Unmarshal(data, &flags)
+		for i, flag := range flags {
+			if flag {
+				orbits = append(orbits, i)
+			}
+		}
+		return orbits, err
+	}
+	var flags map[string]bool
+	err := json.Unmarshal(data, &flags)
+	if err != nil {
+		return nil, err
+	}
+	for key, flag := range flags {
+		orbit, err := strconv.Atoi(key)
+		if err == nil && flag {
+			orbits = append(orbits, orbit)
+		}
+	}
+	sort.Ints(orbits)
+	return orbits, nil
+}
+
+// the oldest exports only list outgoing connections
+func fillIncomingConnections(tree *Tree) {
+	hasIncoming := false
+	for _, node := range tree.Nodes {
+		if len(node.In) > 0 {
+			hasIncoming = true
+			break
+		}
+	}
+	if hasIncoming {
+		return
+	}
+	for _, nodeid := range SortedNodeIds(*tree) {
+		for _, neighbourId := range tree.Nodes[nodeid].Out {
+			neighbour, exists := tree.Nodes[neighbourId]
+			if !exists {
+				continue
+			}
+			neighbour.In = append(neighbour.In, nodeid)
+			tree.Nodes[neighbourId] = neighbour
+		}
+	}
+}
+
+func NewCompactTree(tree Tree) CompactTree {
+	compactTree := CompactTree{
+		Groups: make(map[string]CompactGroup, len(tree.Groups)),
+		Nodes:  make(map[string]CompactNode, len(tree.Nodes)),
+	}
+	for groupId, group := range tree.Groups {
+		compactTree.Groups[groupId] = CompactGroup{Nodes: group.Nodes}
+	}
+	for nodeid, node := range tree.Nodes {
+		compactTree.Nodes[nodeid] = CompactNode{
+			Name:        node.Name,
+			Stats:       node.Stats,
+			IsMastery:   node.IsMastery,
+			IsNotable:   node.IsNotable,
+			IsKeystone:  node.IsKeystone,
+			IsBloodline: node.IsBloodline,
+			IsAttribute: node.IsAttribute,
+			IsWeaponSet: node.IsWeaponSet,
+		}
+	}
+	return compactTree
+}
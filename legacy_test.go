@@ -0,0 +1,154 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// a data.txt export of the 3.x trees, wrapped in javascript, with nodes and
+// orbits as maps and only outgoing connections
+const legacyMapExport = `var passiveSkillTreeData = {
+	"characterData": {"3": {"base_str": 14, "base_dex": 14, "base_int": 32}},
+	"groups": {
+		"1": {"x": 0, "y": 0, "oo": {"0": true}, "n": [100]},
+		"2": {"x": 600, "y": 0, "oo": {"3": true, "0": true, "2": false}, "n": [101, "102"]},
+		"3": {"x": 5000, "y": 5000, "oo": {"0": true}, "n": [200]}
+	},
+	"root": {"id": "root", "out": [100]},
+	"nodes": {
+		"100": {"id": 100, "dn": "WITCH", "spc": [3], "g": 1, "o": 0, "oidx": 0, "out": [101]},
+		"101": {"id": 101, "dn": "Arcane Focus", "not": true, "sd": ["20% increased Spell Damage"], "ia": 10, "g": 2, "o": 3, "oidx": 4, "out": [102]},
+		"102": {"dn": "Caster Mastery", "m": true, "g": 2, "o": 0, "oidx": 0, "out": []},
+		"200": {"id": 200, "dn": "Occultist", "ascendancyName": "Occultist", "isAscendancyStart": true, "passivePointsGranted": 1, "g": 3, "o": 0, "oidx": 0, "out": []}
+	},
+	"min_x": -1000, "min_y": -1000, "max_x": 6000, "max_y": 6000,
+	"constants": {"skillsPerOrbit": [1, 6, 12, 12, 40], "orbitRadii": [0, 82, 162, 335, 493]},
+	"skillSprites": {"normalActive": [{"filename": "skill-0.png", "coords": {}}, {"filename": "skill-1.png", "coords": {}}]},
+	"imageZoomLevels": [0.1246, 0.2109]
+};
+`
+
+// the oldest exports list the nodes and give the orbits as a list of flags
+const legacyListExport = `{
+	"characterData": {},
+	"groups": {"1": {"x": 0, "y": 0, "oo": [true, false, true], "n": [1, 2]}},
+	"nodes": [
+		{"id": 1, "dn": "Start", "g": 1, "o": 0, "oidx": 0, "out": [2], "in": []},
+		{"id": 2, "dn": "Unwavering Stance", "ks": true, "g": 1, "o": 2, "oidx": 3, "out": [], "in": [1]}
+	],
+	"constants": {"skillsPerOrbit": [1, 6, 12], "orbitRadii": [0, 82, 162]},
+	"points": {"totalPoints": 120, "ascendancyPoints": 6}
+}`
+
+func TestDecodeLegacyMapExport(t *testing.T) {
+	tree, err := DecodeTree([]byte(legacyMapExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Classes) != 7 || tree.Classes[3].Name != "Witch" || tree.Classes[3].BaseInt != 32 || tree.Classes[3].Ascendancies[0].Name != "Occultist" {
+		t.Errorf("classes = %+v", tree.Classes)
+	}
+	if tree.Points != legacyPoints {
+		t.Errorf("points = %+v, want the default %+v", tree.Points, legacyPoints)
+	}
+	if orbits := tree.Groups["2"].Orbits; !slices.Equal(orbits, []int{0, 3}) {
+		t.Errorf("orbits = %v, want [0 3]", orbits)
+	}
+	if nodes := tree.Groups["2"].Nodes; !slices.Equal(nodes, []string{"101", "102"}) {
+		t.Errorf("group nodes = %v", nodes)
+	}
+
+	start := tree.Nodes["100"]
+	if start.ClassStartIndex == nil || *start.ClassStartIndex != 3 || start.Skill != 100 {
+		t.Errorf("class start = %+v", start)
+	}
+	notable := tree.Nodes["101"]
+	if !notable.IsNotable || *notable.Name != "Arcane Focus" || notable.GrantedIntelligence != 10 || notable.Orbit != 3 || notable.OrbitIndex != 4 {
+		t.Errorf("notable = %+v", notable)
+	}
+	// incoming connections are filled from the outgoing ones
+	if !slices.Equal(notable.In, []string{"100"}) || !slices.Equal(tree.Nodes["102"].In, []string{"101"}) {
+		t.Errorf("incoming connections = %v, %v", notable.In, tree.Nodes["102"].In)
+	}
+	// nodes without an id take the key of the map
+	if mastery := tree.Nodes["102"]; !mastery.IsMastery || mastery.Skill != 102 {
+		t.Errorf("mastery = %+v", mastery)
+	}
+	ascendancy := tree.Nodes["200"]
+	if ascendancy.AscendancyName == nil || *ascendancy.AscendancyName != "Occultist" || !ascendancy.IsAscendancyStart || ascendancy.GrantedPassivePoints != 1 {
+		t.Errorf("ascendancy start = %+v", ascendancy)
+	}
+	if root := tree.Nodes["root"]; !slices.Equal(root.Out, []string{"100"}) {
+		t.Errorf("root = %+v", root)
+	}
+	if sprite := tree.Sprites["normalActive"]["0.2109"]; sprite.FileName != "skill-1.png" {
+		t.Errorf("sprites = %+v", tree.Sprites)
+	}
+}
+
+func TestDecodeLegacyListExport(t *testing.T) {
+	tree, err := DecodeTree([]byte(legacyListExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Points != (PassivePoints{TotalPoints: 120, AscendancyPoints: 6}) {
+		t.Errorf("points = %+v, want the points of the export", tree.Points)
+	}
+	if orbits := tree.Groups["1"].Orbits; !slices.Equal(orbits, []int{0, 2}) {
+		t.Errorf("orbits = %v, want [0 2]", orbits)
+	}
+	keystone := tree.Nodes["2"]
+	if !keystone.IsKeystone || !slices.Equal(keystone.In, []string{"1"}) {
+		t.Errorf("keystone = %+v", keystone)
+	}
+	if len(tree.Nodes) != 2 {
+		t.Errorf("decoded %d nodes, want 2", len(tree.Nodes))
+	}
+}
+
+func TestProbeExport(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		object string
+		schema exportSchema
+	}{
+		{"current", `{"tree": "Default", "nodes": {"1": {"out": []}}}`, `{"tree": "Default", "nodes": {"1": {"out": []}}}`, currentExport},
+		{"legacy", `{"characterData": {}, "nodes": []}`, `{"characterData": {}, "nodes": []}`, legacyExport},
+		{"wrapped legacy", "var passiveSkillTreeData = {\"characterData\": {}};\nvar opts = {};", `{"characterData": {}}`, legacyExport},
+		{"path of exile 2", `{"tree": "Default", "nodes": {"1": {"connections": []}}}`, `{"tree": "Default", "nodes": {"1": {"connections": []}}}`, poe2Export},
+	}
+	for _, test := range tests {
+		object, schema, err := probeExport([]byte(test.data))
+		if err != nil || string(object) != test.object || schema != test.schema {
+			t.Errorf("%s: probed %q, %d, %v", test.name, object, schema, err)
+		}
+	}
+	for _, data := range []string{"", "no json", `{"tree": `} {
+		if _, _, err := probeExport([]byte(data)); err == nil {
+			t.Errorf("probeExport(%q) should fail", data)
+		}
+	}
+}
+
+func TestLegacyId(t *testing.T) {
+	tests := []struct {
+		data string
+		id   legacyId
+	}{
+		{`100`, "100"},
+		{`"100"`, "100"},
+		{`"root"`, "root"},
+	}
+	for _, test := range tests {
+		var id legacyId
+		err := id.UnmarshalJSON([]byte(test.data))
+		if err != nil || id != test.id {
+			t.Errorf("legacyId(%s) = %q, %v", test.data, id, err)
+		}
+	}
+	var id legacyId
+	if err := id.UnmarshalJSON([]byte(`{}`)); err == nil {
+		t.Error("legacyId accepted an object")
+	}
+}
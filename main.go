@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"math"
 	"net/http"
@@ -14,159 +15,124 @@ import (
 	svg "github.com/ajstarks/svgo"
 )
 
-func fixName(name string) string {
-	return strings.ReplaceAll(name, ".json", ".svg")
-}
-
 func main() {
-	// create dirs if not existing
-	err := os.MkdirAll("svg/atlas", os.ModePerm)
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = os.MkdirAll("svg/passives", os.ModePerm)
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = os.MkdirAll("json/atlas", os.ModePerm)
-	if err != nil {
-		log.Fatal(err)
-	}
-	err = os.MkdirAll("json/passives", os.ModePerm)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// loop over all json files in atlastree and generate svg files
-	entries, err := os.ReadDir("atlastree")
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, file := range entries {
-		if strings.HasSuffix(file.Name(), ".json") {
-			fmt.Printf("Generating SVG for %s\n", file.Name())
-			DrawTree("atlastree/"+file.Name(), "svg/atlas/"+fixName(file.Name()))
-			SaveCompactJson("atlastree/"+file.Name(), "json/atlas/"+file.Name())
-		}
+	command := "all"
+	args := []string{}
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+		args = os.Args[2:]
+	}
+
+	var err error
+	switch command {
+	case "render":
+		err = RunRender(args)
+	case "compact":
+		err = RunCompact(args)
+	case "all":
+		err = RunAll(args)
+	case "html":
+		err = RunHtml(args)
+	case "png":
+		err = RunPng(args)
+	case "tiles":
+		err = RunTiles(args)
+	case "pdf":
+		err = RunPdf(args)
+	case "themes":
+		err = RunThemes(args)
+	case "serve":
+		err = RunServe(args)
+	case "url":
+		err = RunUrl(args)
+	case "path":
+		err = RunPath(args)
+	case "plan":
+		err = RunPlan(args)
+	case "validate":
+		err = RunValidate(args)
+	case "diff":
+		err = RunDiff(args)
+	case "heatmap":
+		err = RunHeatmap(args)
+	case "fetch":
+		err = RunFetch(args)
+	case "sprites":
+		err = RunSprites(args)
+	case "help", "-h", "-help", "--help":
+		PrintUsage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		PrintUsage()
+		os.Exit(2)
 	}
-
-	entries, err = os.ReadDir("skilltree")
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, file := range entries {
-		if strings.HasSuffix(file.Name(), ".json") {
-			fmt.Printf("Generating SVG for %s\n", file.Name())
-			DrawTree("skilltree/"+file.Name(), "svg/passives/"+fixName(file.Name()))
-			SaveCompactJson("skilltree/"+file.Name(), "json/passives/"+file.Name())
-		}
-	}
+}
 
+type DrawOptions struct {
+	Allocation *Allocation
+	Diff       *DiffOverlay
+	Heatmap    *Heatmap
+	// fills the nodes with their icons from the sprite sheets
+	Icons *IconOptions
+	// draws group backgrounds and orbit rings under the connections
+	Groups *GroupOptions
+	// draws the class starts and ascendancy frames under the connections
+	Classes *ClassOptions
+	// writes the names of keystones and notables next to them
+	Labels *LabelOptions
+	// adds titles and data attributes to the nodes, which increases the size
+	Metadata *MetadataOptions
+	// describes the svg with a title, metadata and a legend
+	Document *DocumentOptions
+	// css embedded into the svg, nothing is embedded if empty
+	Style string
+	// url of a stylesheet imported instead of embedding Style
+	StyleLink string
 }
 
 type TreeDrawer struct {
-	s    *svg.SVG
-	Tree Tree
+	s         *svg.SVG
+	Tree      Tree
+	Options   DrawOptions
+	allocated map[int]bool
+	// extra classes for nodes and connections, keyed by node hash and connection key
+	nodeClasses       map[int][]string
+	connectionClasses map[[2]int][]string
+	// icon pattern ids keyed by node hash
+	icons map[int]string
+	// draws the removed nodes and connections of a diff from the old tree
+	previous *TreeDrawer
 }
 
-func InitTreeDrawer(w http.ResponseWriter) *TreeDrawer {
-	w.Header().Set("Content-Type", "image/svg+xml")
-	s := svg.New(w)
-
-	file, err := os.Open("atlastree/3.25.0.json")
-	if err != nil {
-		log.Fatal(err)
+func NewTreeDrawer(s *svg.SVG, tree Tree, options DrawOptions) *TreeDrawer {
+	d := &TreeDrawer{
+		s:                 s,
+		Tree:              tree,
+		Options:           options,
+		nodeClasses:       make(map[int][]string),
+		connectionClasses: make(map[[2]int][]string),
 	}
-	defer file.Close()
-	tree := Tree{}
-	err = json.NewDecoder(file).Decode(&tree)
-	if err != nil {
-		log.Fatal(err)
+	if options.Allocation != nil {
+		d.allocated = options.Allocation.NodeSet(tree)
 	}
-	MoveAscendancyTrees(&tree)
-	s.Start(1000, 1000, fmt.Sprintf("viewBox=\"%d %d %d %d\"", tree.MinX, tree.MinY, tree.MaxX-tree.MinX, tree.MaxY-tree.MinY))
-
-	s.Def()
-	s.Style("text/css", `
-		/* Node styles */
-		circle {
-			fill: #3e3e3e;
-			stroke: #8b8b8b;
-			stroke-width: 2;
-		}
-
-		circle.keystone {
-			fill: #b8860b;
-			stroke: #ffd700;
-			stroke-width: 3;
-		}
-
-		circle.mastery {
-			fill: #4169e1;
-			stroke: #87ceeb;
-			stroke-width: 2;
-		}
-
-		circle.isolated {
-			fill: #ff6b6b;
-			stroke: #ff4757;
-			stroke-width: 2;
-		}
-
-		circle.ascendancy {
-			fill: #9932cc;
-			stroke: #ba55d3;
-			stroke-width: 2;
-		}
-
-		/* Connection styles */
-		line, path {
-			stroke: #666666;
-			stroke-width: 2;
-			fill: none;
-		}
-
-		line.ascendancy, path.ascendancy {
-			stroke: #9932cc;
-			stroke-width: 3;
-		}
-
-		/* Hover effects */
-		circle:hover {
-			stroke-width: 4;
-			filter: brightness(1.2);
-		}
-
-		line:hover, path:hover {
-			stroke-width: 4;
-			filter: brightness(1.2);
-		}
-
-		/* Group orbit styles */
-		circle[fill="none"] {
-			stroke: #228b22;
-			stroke-width: 1;
-			stroke-dasharray: 5,5;
-		}
-
-		/* Mastery image styles */
-		image.mastery {
-			filter: brightness(1.0);
-			border: 2px solid #4169e1;
-			border-radius: 50%;
-		}
+	if options.Diff != nil {
+		d.applyDiff(*options.Diff)
+	}
+	return d
+}
 
-		image.mastery:hover {
-			filter: brightness(1.3);
-			border-width: 3px;
-		}
-	`)
-	s.DefEnd()
+func (d *TreeDrawer) IsAllocated(node Node) bool {
+	return d.allocated[node.Skill]
+}
 
-	return &TreeDrawer{
-		s:    s,
-		Tree: tree,
-	}
+func InitTreeDrawer(w http.ResponseWriter, tree Tree, options DrawOptions) *TreeDrawer {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	s := StartSvg(w, tree, options)
+	return NewTreeDrawer(s, tree, options)
 }
 
 func HasOverlap[T comparable](x, y []T) bool {
@@ -264,28 +230,32 @@ func MoveAscendancyTrees(Tree *Tree) {
 			}
 		}
 	}
+	// the ascendancy trees of both games are stacked in the empty top right
+	// corner of the bounds, the bloodlines in the top left one
 	dist := 1000.0
 	centerX, centerY := float64(Tree.MaxX)-dist, -float64(Tree.MaxY)+dist
 	for _, groupIds := range ascendancyToGroups {
-		largest := Intersect(groupIds, ascendancyCenterGroups)[0]
-		largestGroup := Tree.Groups[largest]
-		for _, groupId := range groupIds {
-			group := Tree.Groups[groupId]
-			group.X = centerX + (group.X - largestGroup.X)
-			group.Y = centerY + (group.Y - largestGroup.Y)
-			Tree.Groups[groupId] = group
-		}
+		MoveGroups(Tree, groupIds, ascendancyCenterGroups, centerX, centerY)
 	}
 	centerX, centerY = float64(Tree.MinX)+dist, -float64(Tree.MaxY)+dist
 	for _, groupIds := range bloodlineToGroups {
-		largest := Intersect(groupIds, bloodlineCenterGroups)[0]
-		largestGroup := Tree.Groups[largest]
-		for _, groupId := range groupIds {
-			group := Tree.Groups[groupId]
-			group.X = centerX + (group.X - largestGroup.X)
-			group.Y = centerY + (group.Y - largestGroup.Y)
-			Tree.Groups[groupId] = group
-		}
+		MoveGroups(Tree, groupIds, bloodlineCenterGroups, centerX, centerY)
+	}
+}
+
+// moves the groups of an ascendancy so that the group of its start node lies
+// at the given centre, ascendancies without a start node are left in place
+func MoveGroups(Tree *Tree, groupIds []string, centerGroups []string, centerX, centerY float64) {
+	starts := Intersect(groupIds, centerGroups)
+	if len(starts) == 0 {
+		return
+	}
+	largestGroup := Tree.Groups[starts[0]]
+	for _, groupId := range groupIds {
+		group := Tree.Groups[groupId]
+		group.X = centerX + (group.X - largestGroup.X)
+		group.Y = centerY + (group.Y - largestGroup.Y)
+		Tree.Groups[groupId] = group
 	}
 }
 
@@ -299,13 +269,30 @@ func GetCoordinates(node Node, tree Tree) (int, int, error) {
 		return 0, 0, fmt.Errorf("group does not exist")
 	}
 	radius := tree.Constants.OrbitRadii[node.Orbit]
-	angle := GetOrbitAngle(node.OrbitIndex, tree.Constants.SkillsPerOrbit[node.Orbit])
+	angle := tree.OrbitAngle(node.Orbit, node.OrbitIndex)
 	x := int(group.X + float64(radius)*math.Sin(angle))
 	y := int(group.Y - float64(radius)*math.Cos(angle))
 
 	return x, y, nil
 }
 
+// the radii nodes are drawn with
+const (
+	PassiveRadius  = 30
+	NotableRadius  = 50
+	KeystoneRadius = 80
+)
+
+func NodeRadius(node Node) int {
+	if node.IsNotable {
+		return NotableRadius
+	}
+	if node.IsKeystone || node.IsWormhole {
+		return KeystoneRadius
+	}
+	return PassiveRadius
+}
+
 func (d *TreeDrawer) DrawNode(node Node) {
 	if !node.ShouldDraw() {
 		return
@@ -319,17 +306,27 @@ func (d *TreeDrawer) DrawNode(node Node) {
 		classes = append(classes, "ascendancy")
 		extras = append(extras, *node.AscendancyName)
 	}
+	if d.IsAllocated(node) {
+		classes = append(classes, "allocated")
+	}
+	classes = append(classes, d.nodeClasses[node.Skill]...)
+	if node.IsAttribute {
+		classes = append(classes, "attribute")
+	}
+	if node.IsWeaponSet {
+		classes = append(classes, "weapon-set")
+	}
 	if node.IsNotable {
-		d.DrawPassive(node, 50, classes, extras)
+		d.DrawPassive(node, NotableRadius, classes, extras)
 	} else if node.IsKeystone || node.IsWormhole {
 		classes = append(classes, "keystone")
-		d.DrawPassive(node, 80, classes, extras)
+		d.DrawPassive(node, KeystoneRadius, classes, extras)
 	} else if node.IsMastery {
 		classes = append(classes, "mastery")
 		extras = append(extras, *node.Name)
-		d.DrawPassive(node, 30, classes, extras)
+		d.DrawPassive(node, PassiveRadius, classes, extras)
 	} else {
-		d.DrawPassive(node, 30, classes, extras)
+		d.DrawPassive(node, PassiveRadius, classes, extras)
 	}
 }
 
@@ -345,11 +342,24 @@ func (d *TreeDrawer) DrawPassive(node Node, radius int, cls []string, extras []s
 	if len(extras) > 0 {
 		attr += fmt.Sprintf(" data-extras=\"%s\"", strings.Join(extras, ","))
 	}
+	if heatmap := d.Options.Heatmap; heatmap != nil {
+		radius = heatmap.Radius(node.Skill, radius)
+		attr += fmt.Sprintf(" data-count=\"%d\" style=\"fill:%s\"", heatmap.Counts[node.Skill], heatmap.Color(node.Skill))
+	} else if id, exists := d.icons[node.Skill]; exists {
+		attr += fmt.Sprintf(" style=\"fill:url(#%s)\"", id)
+	}
+	if metadata := d.Options.Metadata; metadata != nil {
+		if attrs := metadata.Attributes(node); attrs != "" {
+			attr += " " + attrs
+		}
+		d.DrawCircleWithTitle(x, y, radius, attr, NodeTitle(node))
+		return
+	}
 	d.s.Circle(x, y, radius, attr)
 }
 
 func (d *TreeDrawer) DrawConnections(node Node) {
-	if node.GrantedPassivePoints == 2 {
+	if !node.ShouldDrawConnections() {
 		return
 	}
 	for _, neighbourId := range node.Out {
@@ -362,11 +372,26 @@ func (d *TreeDrawer) DrawConnection(node1 Node, node2 Node) {
 	if !node1.ShouldDraw() || !node2.ShouldDraw() || !node1.ShouldConnectTo(node2) {
 		return
 	}
-	attr := fmt.Sprintf("id=\"c-%d-%d\"", node1.Skill, node2.Skill)
+	classes := []string{}
+	extras := []string{}
 	if node1.AscendancyName != nil {
-		attr += fmt.Sprintf(" class=\"ascendancy\" data-extras=\"%s\"", *node1.AscendancyName)
+		classes = append(classes, "ascendancy")
+		extras = append(extras, *node1.AscendancyName)
+	}
+	if d.IsAllocated(node1) && d.IsAllocated(node2) {
+		classes = append(classes, "allocated")
 	}
-	if node1.Group == node2.Group && node1.Orbit == node2.Orbit {
+	classes = append(classes, d.connectionClasses[ConnectionKey(node1.Skill, node2.Skill)]...)
+	attr := fmt.Sprintf("id=\"c-%d-%d\"", node1.Skill, node2.Skill)
+	if len(classes) > 0 {
+		attr += fmt.Sprintf(" class=\"%s\"", strings.Join(classes, " "))
+	}
+	if len(extras) > 0 {
+		attr += fmt.Sprintf(" data-extras=\"%s\"", strings.Join(extras, ","))
+	}
+	if orbit := node1.ConnectionOrbits[strconv.Itoa(node2.Skill)]; orbit != 0 {
+		d.DrawCurve(node1, node2, orbit, attr)
+	} else if node1.Group == node2.Group && node1.Orbit == node2.Orbit {
 		d.DrawArc(node1, node2, attr)
 	} else {
 		d.DrawLine(node1, node2, attr)
@@ -396,8 +421,8 @@ func (d *TreeDrawer) DrawArc(node1 Node, node2 Node, attr string) {
 	}
 
 	radius := d.Tree.Constants.OrbitRadii[node1.Orbit]
-	node1Angle := GetOrbitAngle(node1.OrbitIndex, d.Tree.Constants.SkillsPerOrbit[node1.Orbit])
-	node2Angle := GetOrbitAngle(node2.OrbitIndex, d.Tree.Constants.SkillsPerOrbit[node2.Orbit])
+	node1Angle := d.Tree.OrbitAngle(node1.Orbit, node1.OrbitIndex)
+	node2Angle := d.Tree.OrbitAngle(node2.Orbit, node2.OrbitIndex)
 
 	angleDiff := node2Angle - node1Angle
 	if angleDiff > math.Pi {
@@ -411,6 +436,21 @@ func (d *TreeDrawer) DrawArc(node1 Node, node2 Node, attr string) {
 	d.s.Arc(x1, y1, radius, radius, 0, largeArc, sweep, x2, y2, attr)
 }
 
+// draws a connection between nodes of different groups along the given
+// orbit, used by path of exile 2 trees
+func (d *TreeDrawer) DrawCurve(node1 Node, node2 Node, orbit int, attr string) {
+	x1, y1, err := d.GetCoordinates(node1)
+	if err != nil {
+		return
+	}
+	x2, y2, err := d.GetCoordinates(node2)
+	if err != nil {
+		return
+	}
+	radius := d.Tree.Constants.OrbitRadii[max(orbit, -orbit)]
+	d.s.Arc(x1, y1, radius, radius, 0, false, orbit > 0, x2, y2, attr)
+}
+
 func (d *TreeDrawer) GetCoordinates(node Node) (int, int, error) {
 	return GetCoordinates(node, d.Tree)
 }
@@ -418,63 +458,75 @@ func (d *TreeDrawer) GetCoordinates(node Node) (int, int, error) {
 func (d *TreeDrawer) DrawGroup(group Group) {
 	for _, orbit := range group.Orbits {
 		radius := d.Tree.Constants.OrbitRadii[orbit]
-		d.s.Circle(int(group.X), int(group.Y), radius, "fill:none;stroke:green")
+		if radius == 0 {
+			continue
+		}
+		d.s.Circle(int(group.X), int(group.Y), radius, "class=\"orbit\" fill=\"none\"")
 	}
 }
 
-func SaveCompactJson(fileName string, outFileName string) {
-	file, err := os.Open(fileName)
+func LoadTree(fileName string) (Tree, error) {
+	data, err := os.ReadFile(fileName)
 	if err != nil {
-		log.Fatal(err)
+		return Tree{}, err
 	}
-	defer file.Close()
-	compactTree := CompactTree{}
-	err = json.NewDecoder(file).Decode(&compactTree)
+	tree, err := DecodeTree(data)
 	if err != nil {
-		log.Fatal(err)
+		return Tree{}, fmt.Errorf("failed to decode %s: %w", fileName, err)
 	}
-	outFile, err := os.Create(outFileName)
+	MoveAscendancyTrees(&tree)
+	return tree, nil
+}
+
+func LoadCompactTree(fileName string) (CompactTree, error) {
+	data, err := os.ReadFile(fileName)
 	if err != nil {
-		log.Fatal(err)
+		return CompactTree{}, err
 	}
-	defer outFile.Close()
-	encoder := json.NewEncoder(outFile)
-	encoder.SetIndent("", "")
-	err = encoder.Encode(compactTree)
+	object, schema, err := probeExport(data)
 	if err != nil {
-		log.Fatal(err)
+		return CompactTree{}, fmt.Errorf("failed to decode %s: %w", fileName, err)
 	}
+	if schema != currentExport {
+		tree, err := decodeExport(object, schema)
+		if err != nil {
+			return CompactTree{}, fmt.Errorf("failed to decode %s: %w", fileName, err)
+		}
+		return NewCompactTree(tree), nil
+	}
+	compactTree := CompactTree{}
+	err = json.Unmarshal(object, &compactTree)
+	if err != nil {
+		return CompactTree{}, fmt.Errorf("failed to decode %s: %w", fileName, err)
+	}
+	return compactTree, nil
 }
 
-func DrawTree(fileName string, out string) {
-	file, err := os.Open(fileName)
+func WriteCompactJson(w io.Writer, compactTree CompactTree) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "")
+	return encoder.Encode(compactTree)
+}
+
+func SaveCompactJson(fileName string, outFileName string) {
+	compactTree, err := LoadCompactTree(fileName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer file.Close()
-	tree := Tree{}
-	err = json.NewDecoder(file).Decode(&tree)
+	outFile, err := os.Create(outFileName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	MoveAscendancyTrees(&tree)
-
-	outFile, err := os.Create(out)
+	defer outFile.Close()
+	err = WriteCompactJson(outFile, compactTree)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer outFile.Close()
-
-	s := svg.New(outFile)
-	s.Startraw(fmt.Sprintf("viewBox=\"%d %d %d %d\"", tree.MinX, tree.MinY, tree.MaxX-tree.MinX, tree.MaxY-tree.MinY))
-
-	drawer := &TreeDrawer{
-		s:    s,
-		Tree: tree,
-	}
+}
 
-	nodeids := make([]string, 0, len(drawer.Tree.Nodes))
-	for nodeid := range drawer.Tree.Nodes {
+func SortedNodeIds(tree Tree) []string {
+	nodeids := make([]string, 0, len(tree.Nodes))
+	for nodeid := range tree.Nodes {
 		nodeids = append(nodeids, nodeid)
 	}
 	sort.Slice(nodeids, func(i, j int) bool {
@@ -482,17 +534,100 @@ func DrawTree(fileName string, out string) {
 		intJ, _ := strconv.Atoi(nodeids[j])
 		return intI < intJ
 	})
-	drawer.s.Gid("connections")
+	return nodeids
+}
+
+func (d *TreeDrawer) Draw() {
+	nodeids := SortedNodeIds(d.Tree)
+	if d.Options.Icons != nil && d.Options.Heatmap == nil {
+		d.DrawIconPatterns()
+	}
+	if d.Options.Groups != nil {
+		d.DrawGroups()
+	}
+	if d.Options.Classes != nil {
+		d.DrawClasses()
+	}
+	d.s.Gid("connections")
 	for _, nodeid := range nodeids {
-		node := drawer.Tree.Nodes[nodeid]
-		drawer.DrawConnections(node)
+		node := d.Tree.Nodes[nodeid]
+		d.DrawConnections(node)
+	}
+	if d.previous != nil {
+		d.previous.DrawRemovedConnections()
 	}
-	drawer.s.Gend()
-	drawer.s.Gid("nodes")
+	d.s.Gend()
+	d.s.Gid("nodes")
 	for _, nodeid := range nodeids {
-		node := drawer.Tree.Nodes[nodeid]
-		drawer.DrawNode(node)
+		node := d.Tree.Nodes[nodeid]
+		d.DrawNode(node)
+	}
+	if d.previous != nil {
+		d.previous.DrawRemovedNodes()
+	}
+	d.s.Gend()
+	if d.Options.Labels != nil {
+		d.DrawLabels()
+	}
+	if d.Options.Document != nil && d.Options.Document.Legend {
+		d.DrawLegend()
+	}
+	d.s.End()
+}
+
+func DrawTree(fileName string, out string, options DrawOptions) {
+	tree, err := LoadTree(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outFile, err := os.Create(out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer outFile.Close()
+
+	WriteSvg(outFile, tree, options)
+}
+
+func WriteSvg(w io.Writer, tree Tree, options DrawOptions) {
+	s := StartSvg(w, tree, options)
+	NewTreeDrawer(s, tree, options).Draw()
+}
+
+// returns the bounds of the svg, which also cover the old tree of a diff
+func ViewBox(tree Tree, options DrawOptions) (int, int, int, int) {
+	if options.Diff != nil {
+		tree.MinX = min(tree.MinX, options.Diff.OldTree.MinX)
+		tree.MinY = min(tree.MinY, options.Diff.OldTree.MinY)
+		tree.MaxX = max(tree.MaxX, options.Diff.OldTree.MaxX)
+		tree.MaxY = max(tree.MaxY, options.Diff.OldTree.MaxY)
 	}
-	drawer.s.Gend()
-	drawer.s.End()
+	return tree.MinX, tree.MinY, tree.MaxX, tree.MaxY
+}
+
+// escapes a value for a double quoted css string, the style is written
+// inside a cdata section so the value is not html escaped
+func cssString(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// writes the svg header and embedded style, the caller draws the content
+func StartSvg(w io.Writer, tree Tree, options DrawOptions) *svg.SVG {
+	minX, minY, maxX, maxY := ViewBox(tree, options)
+	s := svg.New(w)
+	s.Startraw(fmt.Sprintf("viewBox=\"%d %d %d %d\"", minX, minY, maxX-minX, maxY-minY))
+	if options.Document != nil && options.Document.Describe {
+		DrawDescription(s, *options.Document)
+	}
+	if options.StyleLink != "" {
+		s.Def()
+		s.Style("text/css", fmt.Sprintf("@import url(\"%s\");", cssString(options.StyleLink)))
+		s.DefEnd()
+	} else if options.Style != "" {
+		s.Def()
+		s.Style("text/css", options.Style)
+		s.DefEnd()
+	}
+	return s
 }
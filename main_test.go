@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func renderSvg(t *testing.T, tree Tree, options DrawOptions) string {
+	t.Helper()
+	var buffer bytes.Buffer
+	WriteSvg(&buffer, tree, options)
+	return buffer.String()
+}
+
+// returns the start tag of the element with the given id, empty if missing
+func svgElement(svg string, id string) string {
+	return regexp.MustCompile(`<[a-z]+ [^>]*id="` + regexp.QuoteMeta(id) + `"[^>]*>`).FindString(svg)
+}
+
+func svgClasses(element string) []string {
+	match := regexp.MustCompile(` class="([^"]*)"`).FindStringSubmatch(element)
+	if match == nil {
+		return nil
+	}
+	return strings.Fields(match[1])
+}
+
+func TestDrawAllocated(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := renderSvg(t, tree, DrawOptions{Allocation: &Allocation{Nodes: []int{101, 102}}})
+	tests := []struct {
+		id        string
+		allocated bool
+	}{
+		{"n-101", true},
+		{"n-102", true},
+		{"n-103", false},
+		// both ends of a connection have to be allocated
+		{"c-101-102", true},
+		{"c-102-103", false},
+		{"c-103-104", false},
+	}
+	for _, test := range tests {
+		element := svgElement(svg, test.id)
+		if element == "" {
+			t.Errorf("%s is not drawn", test.id)
+			continue
+		}
+		if allocated := slices.Contains(svgClasses(element), "allocated"); allocated != test.allocated {
+			t.Errorf("%s allocated = %v, want %v: %s", test.id, allocated, test.allocated, element)
+		}
+	}
+
+	if strings.Contains(renderSvg(t, tree, DrawOptions{}), "allocated") {
+		t.Error("a tree without an allocation has allocated elements")
+	}
+}
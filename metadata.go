@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+)
+
+// the node fields that can be written as data-* attributes
+var MetadataFields = []string{"name", "stats", "type", "ascendancy", "group", "orbit"}
+
+type MetadataOptions struct {
+	Fields []string
+}
+
+func ParseMetadataFields(s string) ([]string, error) {
+	if s == "all" {
+		return MetadataFields, nil
+	}
+	fields := make([]string, 0)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(MetadataFields, field) {
+			return nil, fmt.Errorf("unknown metadata field %q, expected one of %s", field, strings.Join(MetadataFields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func NodeType(node Node) string {
+	switch {
+	case node.IsKeystone:
+		return "keystone"
+	case node.IsNotable:
+		return "notable"
+	case node.IsMastery:
+		return "mastery"
+	case node.IsJewelSocket:
+		return "jewel"
+	case node.IsAscendancyStart:
+		return "ascendancy-start"
+	case node.IsWormhole:
+		return "wormhole"
+	case node.IsAttribute:
+		return "attribute"
+	default:
+		return "normal"
+	}
+}
+
+// escapes an attribute value, keeping line breaks which parsers would
+// otherwise normalize to spaces
+func escapeAttribute(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "&#10;")
+}
+
+func (o MetadataOptions) Attributes(node Node) string {
+	attrs := make([]string, 0, len(o.Fields))
+	for _, field := range o.Fields {
+		value := ""
+		switch field {
+		case "name":
+			if node.Name == nil {
+				continue
+			}
+			value = *node.Name
+		case "stats":
+			if len(node.Stats) == 0 {
+				continue
+			}
+			value = strings.Join(node.Stats, "\n")
+		case "type":
+			value = NodeType(node)
+		case "ascendancy":
+			if node.AscendancyName == nil {
+				continue
+			}
+			value = *node.AscendancyName
+		case "group":
+			value = fmt.Sprintf("%d", node.Group)
+		case "orbit":
+			value = fmt.Sprintf("%d", node.Orbit)
+		}
+		attrs = append(attrs, fmt.Sprintf("data-%s=\"%s\"", field, escapeAttribute(value)))
+	}
+	return strings.Join(attrs, " ")
+}
+
+// the tooltip of a node, its name followed by its stats
+func NodeTitle(node Node) string {
+	lines := make([]string, 0, len(node.Stats)+1)
+	if node.Name != nil {
+		lines = append(lines, *node.Name)
+	}
+	lines = append(lines, node.Stats...)
+	return strings.Join(lines, "\n")
+}
+
+// draws a node circle with a title element, which svgo's Circle can not
+// contain
+func (d *TreeDrawer) DrawCircleWithTitle(x int, y int, radius int, attr string, title string) {
+	fmt.Fprintf(d.s.Writer, "<circle cx=\"%d\" cy=\"%d\" r=\"%d\" %s>", x, y, radius, attr)
+	d.s.Title(title)
+	fmt.Fprintln(d.s.Writer, "</circle>")
+}
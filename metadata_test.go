@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMetadataFields(t *testing.T) {
+	tests := []struct {
+		s      string
+		fields []string
+	}{
+		{"all", MetadataFields},
+		{"name, stats,", []string{"name", "stats"}},
+		{"orbit,group", []string{"orbit", "group"}},
+		{"", []string{}},
+	}
+	for _, test := range tests {
+		fields, err := ParseMetadataFields(test.s)
+		if err != nil || !slices.Equal(fields, test.fields) {
+			t.Errorf("ParseMetadataFields(%q) = %v, %v, want %v", test.s, fields, err, test.fields)
+		}
+	}
+	if _, err := ParseMetadataFields("name,colour"); err == nil {
+		t.Error("ParseMetadataFields accepted an unknown field")
+	}
+}
+
+func TestNodeType(t *testing.T) {
+	tests := []struct {
+		node Node
+		kind string
+	}{
+		{Node{}, "normal"},
+		{Node{IsKeystone: true}, "keystone"},
+		{Node{IsNotable: true}, "notable"},
+		{Node{IsMastery: true}, "mastery"},
+		{Node{IsJewelSocket: true}, "jewel"},
+		{Node{IsAscendancyStart: true}, "ascendancy-start"},
+		{Node{IsWormhole: true}, "wormhole"},
+		{Node{IsAttribute: true}, "attribute"},
+		// keystones take precedence over the other flags
+		{Node{IsKeystone: true, IsNotable: true}, "keystone"},
+	}
+	for _, test := range tests {
+		if kind := NodeType(test.node); kind != test.kind {
+			t.Errorf("NodeType(%+v) = %q, want %q", test.node, kind, test.kind)
+		}
+	}
+}
+
+func TestMetadataAttributes(t *testing.T) {
+	name := "Arcane \"Focus\""
+	ascendancy := "Occultist"
+	node := Node{
+		Name:           &name,
+		Stats:          []string{"20% increased Spell Damage", "+10 to Intelligence"},
+		IsNotable:      true,
+		AscendancyName: &ascendancy,
+		Group:          2,
+		Orbit:          3,
+	}
+	options := MetadataOptions{Fields: MetadataFields}
+	want := `data-name="Arcane &#34;Focus&#34;" data-stats="20% increased Spell Damage&#10;+10 to Intelligence" data-type="notable" data-ascendancy="Occultist" data-group="2" data-orbit="3"`
+	if attrs := options.Attributes(node); attrs != want {
+		t.Errorf("Attributes = %s, want %s", attrs, want)
+	}
+	// missing values are left out
+	if attrs := options.Attributes(Node{Group: 1}); attrs != `data-type="normal" data-group="1" data-orbit="0"` {
+		t.Errorf("Attributes of an empty node = %s", attrs)
+	}
+	if attrs := (MetadataOptions{}).Attributes(node); attrs != "" {
+		t.Errorf("Attributes without fields = %s", attrs)
+	}
+}
+
+func TestNodeTitle(t *testing.T) {
+	name := "Arcane Focus"
+	tests := []struct {
+		node  Node
+		title string
+	}{
+		{Node{Name: &name, Stats: []string{"20% increased Spell Damage", "+10 to Intelligence"}}, "Arcane Focus\n20% increased Spell Damage\n+10 to Intelligence"},
+		{Node{Name: &name}, "Arcane Focus"},
+		{Node{Stats: []string{"+10 to Intelligence"}}, "+10 to Intelligence"},
+		{Node{}, ""},
+	}
+	for _, test := range tests {
+		if title := NodeTitle(test.node); title != test.title {
+			t.Errorf("NodeTitle = %q, want %q", title, test.title)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// paper sizes in points, portrait
+var PaperSizes = map[string][2]float64{
+	"a0":      {2384, 3370},
+	"a1":      {1684, 2384},
+	"a2":      {1191, 1684},
+	"a3":      {842, 1191},
+	"a4":      {595, 842},
+	"letter":  {612, 792},
+	"legal":   {612, 1008},
+	"tabloid": {792, 1224},
+	"arch-c":  {1296, 1728},
+	"arch-d":  {1728, 2592},
+	"arch-e":  {2592, 3456},
+}
+
+func PaperNames() []string {
+	names := make([]string, 0, len(PaperSizes))
+	for name := range PaperSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type PosterOptions struct {
+	Title       string
+	Subtitle    string
+	Paper       string
+	Orientation string
+	Columns     int
+	Rows        int
+	Background  color.RGBA
+}
+
+// the margin left blank on every page for the printer, in points
+const posterMargin = 36
+
+// Helvetica glyph widths of the printable ascii characters, in thousandths of
+// the font size
+var helveticaWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+func textWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		if r >= 32 && int(r-32) < len(helveticaWidths) {
+			width += helveticaWidths[r-32]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// the characters outside of latin-1 that WinAnsiEncoding places elsewhere
+var winAnsiCharacters = map[rune]byte{
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '…': 0x85,
+}
+
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 32 && r < 127:
+			b.WriteByte(byte(r))
+		case r >= 160 && r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			if c, exists := winAnsiCharacters[r]; exists {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func pdfNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// translucent colors are painted opaque, the content streams do not use
+// transparency
+func pdfColor(c color.RGBA, operator string) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("%s %s %s %s", pdfNumber(float64(n.R)/255), pdfNumber(float64(n.G)/255), pdfNumber(float64(n.B)/255), operator)
+}
+
+// content builds a pdf content stream
+type content struct {
+	bytes.Buffer
+}
+
+func (c *content) op(format string, args ...any) {
+	fmt.Fprintf(c, format, args...)
+	c.WriteByte('\n')
+}
+
+// circles are approximated by four bezier curves
+func (c *content) circle(x, y, r float64) {
+	k := 0.5523 * r
+	n := pdfNumber
+	c.op("%s %s m", n(x+r), n(y))
+	c.op("%s %s %s %s %s %s c", n(x+r), n(y+k), n(x+k), n(y+r), n(x), n(y+r))
+	c.op("%s %s %s %s %s %s c", n(x-k), n(y+r), n(x-r), n(y+k), n(x-r), n(y))
+	c.op("%s %s %s %s %s %s c", n(x-r), n(y-k), n(x-k), n(y-r), n(x), n(y-r))
+	c.op("%s %s %s %s %s %s c", n(x+k), n(y-r), n(x+r), n(y-k), n(x+r), n(y))
+}
+
+func (c *content) shape(shape Shape) {
+	if !shape.HasFill && !shape.HasStroke {
+		return
+	}
+	if shape.HasFill {
+		c.op("%s", pdfColor(shape.Fill, "rg"))
+	}
+	if shape.HasStroke {
+		c.op("%s", pdfColor(shape.Stroke, "RG"))
+		c.op("%s w", pdfNumber(shape.StrokeWidth))
+	}
+	if shape.Circle {
+		c.circle(shape.Points[0][0], shape.Points[0][1], shape.Radius)
+		switch {
+		case shape.HasFill && shape.HasStroke:
+			c.op("b")
+		case shape.HasFill:
+			c.op("f")
+		default:
+			c.op("s")
+		}
+		return
+	}
+	if !shape.HasStroke || len(shape.Points) < 2 {
+		return
+	}
+	for i, p := range shape.Points {
+		operator := "l"
+		if i == 0 {
+			operator = "m"
+		}
+		c.op("%s %s %s", pdfNumber(p[0]), pdfNumber(p[1]), operator)
+	}
+	c.op("S")
+}
+
+// writes a text at a position of a y down coordinate system, like the svg
+func (c *content) text(text Text) {
+	if !text.HasFill || text.Content == "" {
+		return
+	}
+	x, y := text.X, text.Y
+	switch text.Anchor {
+	case "middle":
+		x -= textWidth(text.Content, text.FontSize) / 2
+	case "end":
+		x -= textWidth(text.Content, text.FontSize)
+	}
+	if text.Baseline == "middle" || text.Baseline == "central" {
+		y += text.FontSize * 0.35
+	}
+	c.op("%s", pdfColor(text.Fill, "rg"))
+	c.op("BT /F1 %s Tf 1 0 0 -1 %s %s Tm %s Tj ET", pdfNumber(text.FontSize), pdfNumber(x), pdfNumber(y), pdfString(text.Content))
+}
+
+// pdfWriter writes numbered objects and the cross reference table
+type pdfWriter struct {
+	w       io.Writer
+	written int
+	offsets []int
+	err     error
+}
+
+func (p *pdfWriter) write(s string) {
+	if p.err != nil {
+		return
+	}
+	n, err := io.WriteString(p.w, s)
+	p.written += n
+	p.err = err
+}
+
+// objects must be written in the order of their ids, starting at 1
+func (p *pdfWriter) object(body string) {
+	p.offsets = append(p.offsets, p.written)
+	p.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", len(p.offsets), body))
+}
+
+func (p *pdfWriter) stream(dict string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+	p.object(fmt.Sprintf("<< %s /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", dict, compressed.Len(), compressed.String()))
+}
+
+func (p *pdfWriter) finish(root int, info int) error {
+	xref := p.written
+	p.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1))
+	for _, offset := range p.offsets {
+		p.write(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	p.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, root, info, xref))
+	return p.err
+}
+
+// PageSize returns the size of the pages of a poster, in points
+func (o PosterOptions) PageSize(viewBox [4]float64) ([2]float64, error) {
+	size, exists := PaperSizes[o.Paper]
+	if !exists {
+		return size, fmt.Errorf("unknown paper size %q, expected one of %s", o.Paper, strings.Join(PaperNames(), ", "))
+	}
+	landscape := false
+	switch o.Orientation {
+	case "landscape":
+		landscape = true
+	case "portrait":
+	case "", "auto":
+		// the poster is as wide as the tree compared to the pages it spans
+		landscape = viewBox[2]*float64(o.Rows) > viewBox[3]*float64(o.Columns)
+	default:
+		return size, fmt.Errorf("unknown orientation %q, expected auto, portrait or landscape", o.Orientation)
+	}
+	if landscape {
+		size[0], size[1] = size[1], size[0]
+	}
+	return size, nil
+}
+
+// WritePoster writes a pdf poster of a tree. The poster is laid out once and
+// split across Columns x Rows pages, each showing its part within the printer
+// margins so the trimmed pages can be put together.
+func WritePoster(w io.Writer, tree Tree, drawOptions DrawOptions, options PosterOptions) error {
+	drawOptions = drawOptions.ForRaster()
+	if options.Columns <= 0 || options.Rows <= 0 {
+		return fmt.Errorf("invalid page count %dx%d", options.Columns, options.Rows)
+	}
+	var svgBuffer bytes.Buffer
+	WriteSvg(&svgBuffer, tree, drawOptions)
+	scene, err := ParseScene(svgBuffer.Bytes())
+	if err != nil {
+		return err
+	}
+	pageSize, err := options.PageSize(scene.ViewBox)
+	if err != nil {
+		return err
+	}
+	areaWidth, areaHeight := pageSize[0]-2*posterMargin, pageSize[1]-2*posterMargin
+	width, height := areaWidth*float64(options.Columns), areaHeight*float64(options.Rows)
+
+	var c content
+	c.op("%s", pdfColor(options.Background, "rg"))
+	c.op("0 0 %s %s re f", pdfNumber(width), pdfNumber(height))
+	c.op("1 J 1 j")
+
+	// the title at the top, the legend at the bottom and the tree in between,
+	// colored like the legend of the svg
+	rules := ParseStyle(drawOptions.Style)
+	titleText, err := NewText(rules, map[string]string{"class": "legend-title"}, options.Title)
+	if err != nil {
+		return err
+	}
+	labelText, err := NewText(rules, map[string]string{"class": "legend-label"}, "")
+	if err != nil {
+		return err
+	}
+	titleSize := min(width, height) / 30
+	legendSize := titleSize / 2
+	c.op("%s", pdfColor(titleText.Fill, "rg"))
+	c.op("BT /F2 %s Tf %s %s Td %s Tj ET", pdfNumber(titleSize), pdfNumber(titleSize), pdfNumber(height-titleSize*1.5), pdfString(options.Title))
+	c.op("%s", pdfColor(labelText.Fill, "rg"))
+	if options.Subtitle != "" {
+		c.op("BT /F1 %s Tf %s %s Td %s Tj ET", pdfNumber(legendSize), pdfNumber(titleSize), pdfNumber(height-titleSize*1.5-legendSize*1.6), pdfString(options.Subtitle))
+	}
+	// ascendancy trees are moved on top of each other, only the allocated one
+	// is printed and without one they are left out of the legend too
+	ascendancy := ""
+	if drawOptions.Allocation != nil {
+		ascendancy = drawOptions.Allocation.Ascendancy
+	}
+	x := titleSize
+	for _, entry := range LegendEntries(tree, drawOptions) {
+		if ascendancy == "" && slices.Contains(entry.Classes, "ascendancy") {
+			continue
+		}
+		shape, err := NewShape(rules, "circle", map[string]string{"class": strings.Join(entry.Classes, " ")})
+		if err != nil {
+			return err
+		}
+		// the swatches keep the relative node sizes
+		shape.Radius = legendSize * 0.6 * float64(entry.Radius) / NotableRadius
+		shape.StrokeWidth = legendSize / 15
+		shape.Points = [][2]float64{{x + legendSize*0.6, titleSize}}
+		c.shape(shape)
+		c.op("%s", pdfColor(labelText.Fill, "rg"))
+		c.op("BT /F1 %s Tf %s %s Td %s Tj ET", pdfNumber(legendSize), pdfNumber(x+legendSize*1.6), pdfNumber(titleSize-legendSize*0.35), pdfString(entry.Label))
+		x += legendSize*2.6 + textWidth(entry.Label, legendSize)
+	}
+
+	treeTop, treeBottom := height-titleSize*3.5, titleSize*2.5
+	viewBox := scene.ViewBox
+	scale := min((width-2*titleSize)/viewBox[2], (treeTop-treeBottom)/viewBox[3])
+	originX := (width-viewBox[2]*scale)/2 - viewBox[0]*scale
+	originY := treeTop - (treeTop-treeBottom-viewBox[3]*scale)/2 + viewBox[1]*scale
+	c.op("q %s 0 0 %s %s %s cm", pdfNumber(scale), pdfNumber(-scale), pdfNumber(originX), pdfNumber(originY))
+	for _, shape := range scene.Shapes {
+		if shape.Ascendancy == "" || shape.Ascendancy == ascendancy {
+			c.shape(shape)
+		}
+	}
+	for _, text := range scene.Texts {
+		if text.Ascendancy == "" || text.Ascendancy == ascendancy {
+			c.text(text)
+		}
+	}
+	c.op("Q")
+
+	p := &pdfWriter{w: w}
+	p.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	pages := options.Columns * options.Rows
+	const catalog, pageTree, regular, bold, info, poster, firstPage = 1, 2, 3, 4, 5, 6, 7
+	kids := make([]string, 0, pages)
+	for i := 0; i < pages; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+	p.object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pageTree))
+	p.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	p.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	p.object(fmt.Sprintf("<< /Title %s /Subject %s /Producer (treegen) >>", pdfString(options.Title), pdfString(options.Subtitle)))
+	fonts := fmt.Sprintf("/Font << /F1 %d 0 R /F2 %d 0 R >>", regular, bold)
+	p.stream(fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [0 0 %s %s] /Resources << %s >>", pdfNumber(width), pdfNumber(height), fonts), c.Bytes())
+
+	for row := 0; row < options.Rows; row++ {
+		for column := 0; column < options.Columns; column++ {
+			var page content
+			page.op("q 1 0 0 1 %d %d cm", posterMargin, posterMargin)
+			page.op("0 0 %s %s re W n", pdfNumber(areaWidth), pdfNumber(areaHeight))
+			page.op("1 0 0 1 %s %s cm", pdfNumber(-areaWidth*float64(column)), pdfNumber(-areaHeight*float64(options.Rows-1-row)))
+			page.op("/Poster Do Q")
+			if pages > 1 {
+				// tells where a page goes when putting the poster together
+				page.op("0.5 g BT /F1 8 Tf %d %d Td %s Tj ET", posterMargin, posterMargin/2, pdfString(fmt.Sprintf("Row %d, column %d of %dx%d", row+1, column+1, options.Columns, options.Rows)))
+			}
+			p.object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << /Poster %d 0 R >> %s >> /Contents %d 0 R >>",
+				pageTree, pdfNumber(pageSize[0]), pdfNumber(pageSize[1]), poster, fonts, len(p.offsets)+2))
+			p.stream("", page.Bytes())
+		}
+	}
+	return p.finish(catalog, info)
+}
+
+func ParsePageGrid(s string) (int, int, error) {
+	columns, rows, found := strings.Cut(strings.ToLower(s), "x")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid pages %q, expected columns x rows like 2x2", s)
+	}
+	c, err1 := strconv.Atoi(columns)
+	r, err2 := strconv.Atoi(rows)
+	if err1 != nil || err2 != nil || c <= 0 || r <= 0 {
+		return 0, 0, fmt.Errorf("invalid pages %q, expected columns x rows like 2x2", s)
+	}
+	return c, r, nil
+}
+
+func (c Config) PdfPath(file TreeFile) string {
+	return filepath.Join(c.OutputDir, "pdf", file.Kind.Name, file.Version+".pdf")
+}
+
+func RunPdf(args []string) error {
+	fs, config := NewFlagSet("pdf")
+	drawOptions := AddDrawFlags(fs)
+	paper := fs.String("paper", "a1", "paper size of the pages: "+strings.Join(PaperNames(), ", "))
+	orientation := fs.String("orientation", "auto", "page orientation: auto, portrait or landscape")
+	pages := fs.String("pages", "1x1", "split the poster across columns x rows pages")
+	background := fs.String("background", "", "background color of the poster (default the background of the theme)")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	options, err := drawOptions()
+	if err != nil {
+		return err
+	}
+	// posters always carry the names of keystones and notables
+	if options.Labels == nil {
+		options.Labels = &LabelOptions{FontSize: 40}
+	}
+	columns, rows, err := ParsePageGrid(*pages)
+	if err != nil {
+		return err
+	}
+	if _, exists := PaperSizes[*paper]; !exists {
+		return fmt.Errorf("unknown paper size %q, expected one of %s", *paper, strings.Join(PaperNames(), ", "))
+	}
+	if !slices.Contains([]string{"auto", "portrait", "landscape"}, *orientation) {
+		return fmt.Errorf("unknown orientation %q, expected auto, portrait or landscape", *orientation)
+	}
+	backgroundColor, err := RasterBackground(*background, options.ForRaster())
+	if err != nil {
+		return err
+	}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return err
+	}
+	err = MakeOutputDirs(cfg, "pdf")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Generating PDF poster for %s %s\n", file.Kind.Name, file.Version)
+		tree, err := LoadTree(file.Path)
+		if err != nil {
+			return err
+		}
+		posterOptions := PosterOptions{
+			Title:       fmt.Sprintf("%s %s", file.Kind.Title, file.Version),
+			Subtitle:    posterSubtitle(options),
+			Paper:       *paper,
+			Orientation: *orientation,
+			Columns:     columns,
+			Rows:        rows,
+			Background:  backgroundColor,
+		}
+		outFile, err := os.Create(cfg.PdfPath(file))
+		if err != nil {
+			return err
+		}
+		err = WritePoster(outFile, tree, options, posterOptions)
+		outFile.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func posterSubtitle(options DrawOptions) string {
+	if options.Allocation == nil {
+		return ""
+	}
+	subtitle := fmt.Sprintf("%d allocated nodes", len(options.Allocation.Nodes))
+	if options.Allocation.Ascendancy != "" {
+		subtitle += ", " + options.Allocation.Ascendancy
+	}
+	return subtitle
+}
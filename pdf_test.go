@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestPdfString(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"Witch", "(Witch)"},
+		{`a (b) \c`, `(a \(b\) \\c)`},
+		{"Mjölner", `(Mj\366lner)`},
+		{"Witch – Occultist", `(Witch \226 Occultist)`},
+		{"日本", "(??)"},
+	}
+	for _, test := range tests {
+		if s := pdfString(test.s); s != test.want {
+			t.Errorf("pdfString(%q) = %s, want %s", test.s, s, test.want)
+		}
+	}
+}
+
+func TestPdfNumber(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want string
+	}{
+		{0, "0"},
+		{12, "12"},
+		{1.5, "1.5"},
+		{0.1234, "0.123"},
+		{-0.0001, "0"},
+		{-2.25, "-2.25"},
+	}
+	for _, test := range tests {
+		if s := pdfNumber(test.f); s != test.want {
+			t.Errorf("pdfNumber(%v) = %s, want %s", test.f, s, test.want)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width float64
+	}{
+		{"", 0},
+		{"i", 2.22},
+		{"WITCH", (944 + 278 + 611 + 722 + 722) * 0.01},
+		// characters without a known width are as wide as a digit
+		{"ö", 5.56},
+	}
+	for _, test := range tests {
+		if width := textWidth(test.s, 10); fmt.Sprintf("%.3f", width) != fmt.Sprintf("%.3f", test.width) {
+			t.Errorf("textWidth(%q) = %f, want %f", test.s, width, test.width)
+		}
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	wide := [4]float64{0, 0, 2000, 1000}
+	tests := []struct {
+		options PosterOptions
+		viewBox [4]float64
+		size    [2]float64
+	}{
+		{PosterOptions{Paper: "a4", Columns: 1, Rows: 1}, wide, [2]float64{842, 595}},
+		{PosterOptions{Paper: "a4", Columns: 1, Rows: 1}, [4]float64{0, 0, 1000, 2000}, [2]float64{595, 842}},
+		// two columns make a wide tree fit on portrait pages
+		{PosterOptions{Paper: "a4", Columns: 2, Rows: 1}, [4]float64{0, 0, 1500, 1000}, [2]float64{595, 842}},
+		{PosterOptions{Paper: "letter", Orientation: "portrait", Columns: 1, Rows: 1}, wide, [2]float64{612, 792}},
+		{PosterOptions{Paper: "a3", Orientation: "landscape", Columns: 1, Rows: 1}, [4]float64{0, 0, 1, 2}, [2]float64{1191, 842}},
+	}
+	for _, test := range tests {
+		size, err := test.options.PageSize(test.viewBox)
+		if err != nil || size != test.size {
+			t.Errorf("PageSize(%+v, %v) = %v, %v, want %v", test.options, test.viewBox, size, err, test.size)
+		}
+	}
+	for _, options := range []PosterOptions{{Paper: "a5"}, {Paper: "a4", Orientation: "sideways"}} {
+		if _, err := options.PageSize(wide); err == nil {
+			t.Errorf("PageSize(%+v) should fail", options)
+		}
+	}
+}
+
+func TestParsePageGrid(t *testing.T) {
+	tests := []struct {
+		s             string
+		columns, rows int
+	}{
+		{"1x1", 1, 1},
+		{"3X2", 3, 2},
+	}
+	for _, test := range tests {
+		columns, rows, err := ParsePageGrid(test.s)
+		if err != nil || columns != test.columns || rows != test.rows {
+			t.Errorf("ParsePageGrid(%q) = %d, %d, %v", test.s, columns, rows, err)
+		}
+	}
+	for _, s := range []string{"2", "0x1", "1x-1", "ax2", ""} {
+		if _, _, err := ParsePageGrid(s); err == nil {
+			t.Errorf("ParsePageGrid(%q) should fail", s)
+		}
+	}
+}
+
+func TestWritePoster(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	options := PosterOptions{Title: "Passive tree 3.25", Paper: "a4", Columns: 2, Rows: 3}
+	err = WritePoster(&buffer, tree, DrawOptions{Style: DefaultStyle}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("not a pdf: %q ... %q", data[:min(len(data), 20)], data[max(0, len(data)-20):])
+	}
+	if !bytes.Contains(data, []byte("/Type /Pages /Kids [7 0 R 9 0 R 11 0 R 13 0 R 15 0 R 17 0 R] /Count 6")) {
+		t.Error("the page tree does not list the 6 pages")
+	}
+	if count := bytes.Count(data, []byte("/Type /Page ")); count != 6 {
+		t.Errorf("wrote %d pages, want 6", count)
+	}
+	if !bytes.Contains(data, []byte("/Title (Passive tree 3.25)")) {
+		t.Error("the title is missing from the document information")
+	}
+
+	// every object, the six shared ones and a page and its contents for each
+	// page, is where the cross-reference table says it is
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if match == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n0 19\n")) {
+		t.Fatalf("startxref %d does not point at a table of 19 entries", xref)
+	}
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	if len(offsets) != 18 {
+		t.Fatalf("found %d object offsets, want 18", len(offsets))
+	}
+	for i, offset := range offsets {
+		position, _ := strconv.Atoi(string(offset[1]))
+		if !bytes.HasPrefix(data[position:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))) {
+			t.Errorf("object %d is not at offset %d", i+1, position)
+		}
+	}
+
+	for _, options := range []PosterOptions{{Paper: "a4", Columns: 0, Rows: 1}, {Paper: "b5", Columns: 1, Rows: 1}} {
+		if err := WritePoster(&bytes.Buffer{}, tree, DrawOptions{Style: DefaultStyle}, options); err == nil {
+			t.Errorf("WritePoster(%+v) should fail", options)
+		}
+	}
+}
+
+// returns the inflated contents of all streams of a pdf
+func pdfStreams(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	streams := make([][]byte, 0)
+	for _, match := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(data, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(match[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+func TestPosterLegend(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		allocation *Allocation
+		ascendancy bool
+	}{
+		// the overlapping ascendancy trees are not printed without a chosen one
+		{"no ascendancy", nil, false},
+		{"no ascendancy with nodes", &Allocation{Nodes: []int{101}}, false},
+		{"ascendancy", &Allocation{Ascendancy: "Occultist"}, true},
+	}
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		options := PosterOptions{Title: "Passive tree 3.25", Paper: "a4", Columns: 1, Rows: 1}
+		err := WritePoster(&buffer, tree, DrawOptions{Style: DefaultStyle, Allocation: test.allocation}, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, stream := range pdfStreams(t, buffer.Bytes()) {
+			if bytes.Contains(stream, []byte("(Ascendancy) Tj")) {
+				found = true
+			}
+		}
+		if found != test.ascendancy {
+			t.Errorf("%s: legend lists ascendancies %v, want %v", test.name, found, test.ascendancy)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"treegen/internal/shareurl"
+)
+
+type Plan struct {
+	ClassIndex int
+	Ascendancy string
+	Targets    []int
+	// allocated nodes without the class and ascendancy starts
+	Nodes            []int
+	Points           int
+	AscendancyPoints int
+	// available points including points granted by allocated nodes
+	TotalPoints           int
+	TotalAscendancyPoints int
+}
+
+func (p Plan) Allocation() Allocation {
+	return Allocation{Nodes: p.Nodes, Ascendancy: p.Ascendancy}
+}
+
+func (p Plan) ExceedsPoints() bool {
+	return p.Points > p.TotalPoints || p.AscendancyPoints > p.TotalAscendancyPoints
+}
+
+// PlanAllocation approximates the smallest connected allocation that reaches
+// all targets. It greedily connects the closest remaining target to the
+// allocation until all targets are reached and then drops leaves that are
+// neither targets nor previously allocated. Without a chosen ascendancy the
+// plan takes the ascendancy of the targets, if any.
+func (g *Graph) PlanAllocation(classIndex int, ascendancy string, allocated []int, targets []int) (Plan, error) {
+	plan := Plan{ClassIndex: classIndex, Targets: targets}
+	roots, ascendancy, err := g.StartNodes(classIndex, ascendancy, targets)
+	if err != nil {
+		return plan, err
+	}
+	plan.Ascendancy = ascendancy
+	current := append(append([]int{}, roots...), allocated...)
+	inPlan := make(map[int]bool)
+	for _, hash := range current {
+		inPlan[hash] = true
+	}
+
+	remaining := make([]int, 0, len(targets))
+	for _, target := range targets {
+		if !inPlan[target] {
+			remaining = append(remaining, target)
+		}
+	}
+	for len(remaining) > 0 {
+		bestIndex := -1
+		var bestPath []int
+		for i, target := range remaining {
+			path, err := g.ShortestPath(current, target)
+			if err != nil {
+				return plan, fmt.Errorf("cannot reach %s: %w", g.NodeName(target), err)
+			}
+			if bestIndex < 0 || len(path) < len(bestPath) {
+				bestIndex = i
+				bestPath = path
+			}
+		}
+		for _, hash := range bestPath {
+			if !inPlan[hash] {
+				inPlan[hash] = true
+				current = append(current, hash)
+			}
+		}
+		remaining = append(remaining[:bestIndex], remaining[bestIndex+1:]...)
+	}
+
+	keep := make(map[int]bool)
+	for _, hash := range append(append(append([]int{}, roots...), allocated...), targets...) {
+		keep[hash] = true
+	}
+	g.pruneLeaves(inPlan, keep)
+
+	for hash := range inPlan {
+		node := g.Nodes[hash]
+		if node.ClassStartIndex != nil || node.IsAscendancyStart {
+			continue
+		}
+		plan.Nodes = append(plan.Nodes, hash)
+		if node.AscendancyName != nil {
+			plan.AscendancyPoints++
+		} else {
+			plan.Points++
+		}
+		plan.TotalPoints += node.GrantedPassivePoints
+	}
+	sort.Ints(plan.Nodes)
+	plan.TotalPoints += g.Tree.Points.TotalPoints
+	plan.TotalAscendancyPoints = g.Tree.Points.AscendancyPoints
+	return plan, nil
+}
+
+// removes allocated leaves that are not needed to keep the kept nodes connected
+func (g *Graph) pruneLeaves(allocated map[int]bool, keep map[int]bool) {
+	for changed := true; changed; {
+		changed = false
+		for hash := range allocated {
+			if keep[hash] || g.Nodes[hash].IsMastery {
+				continue
+			}
+			degree := 0
+			for _, neighbour := range g.adjacency[hash] {
+				if allocated[neighbour] {
+					degree++
+				}
+			}
+			if degree <= 1 && !g.supportsMastery(hash, allocated) {
+				delete(allocated, hash)
+				changed = true
+			}
+		}
+	}
+}
+
+// reports whether hash is the only allocated node in the group of an allocated mastery
+func (g *Graph) supportsMastery(hash int, allocated map[int]bool) bool {
+	group := g.Tree.Groups[strconv.Itoa(g.Nodes[hash].Group)]
+	hasMastery := false
+	others := 0
+	for _, nodeid := range group.Nodes {
+		other, err := strconv.Atoi(nodeid)
+		if err != nil || !allocated[other] || other == hash {
+			continue
+		}
+		if g.Nodes[other].IsMastery {
+			hasMastery = true
+		} else {
+			others++
+		}
+	}
+	return hasMastery && others == 0
+}
+
+func RunPlan(args []string) error {
+	fs, config := NewFlagSet("plan")
+	className := fs.String("class", "", "class or ascendancy to plan for")
+	ascendancy := fs.String("ascendancy", "", "ascendancy to plan for")
+	nodes := fs.String("nodes", "", "comma separated list of already allocated node hashes")
+	to := fs.String("to", "", "comma separated list of target node hashes or names")
+	svgOut := fs.String("svg", "", "write the tree with the planned allocation highlighted to this file")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	file, err := SelectTreeFile(cfg)
+	if err != nil {
+		return err
+	}
+	tree, err := LoadTree(file.Path)
+	if err != nil {
+		return err
+	}
+	graph := NewGraph(tree)
+
+	classIndex, err := graph.ClassIndex(*className)
+	if err != nil {
+		return err
+	}
+	allocated, err := ParseNodeList(*nodes)
+	if err != nil {
+		return err
+	}
+	targets := make([]int, 0)
+	for _, ref := range strings.Split(*to, ",") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		target, err := graph.FindNode(ref)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no targets given, use -to")
+	}
+
+	plan, err := graph.PlanAllocation(classIndex, *ascendancy, allocated, targets)
+	if err != nil {
+		return err
+	}
+	build, err := NewPassiveBuild(tree, tree.Classes[classIndex].Name, plan.Ascendancy, plan.Nodes)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Passive points: %d / %d\n", plan.Points, plan.TotalPoints)
+	if plan.Ascendancy != "" {
+		fmt.Printf("Ascendancy points: %d / %d\n", plan.AscendancyPoints, plan.TotalAscendancyPoints)
+	}
+	if plan.ExceedsPoints() {
+		fmt.Println("Warning: the plan needs more points than are available")
+	}
+	names := make([]string, len(plan.Nodes))
+	for i, hash := range plan.Nodes {
+		names[i] = strconv.Itoa(hash)
+	}
+	fmt.Printf("Nodes: %s\n", strings.Join(names, ","))
+	// atlas trees and cluster jewel nodes have hashes a share url cannot hold
+	if url, err := shareurl.Encode(build.Build); err != nil {
+		log.Printf("warning: no share url for the plan: %v", err)
+	} else {
+		fmt.Printf("URL: %s\n", url)
+	}
+
+	if *svgOut != "" {
+		outFile, err := os.Create(*svgOut)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+		allocation := plan.Allocation()
+		WriteSvg(outFile, tree, DrawOptions{Allocation: &allocation})
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlanAllocation(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := NewGraph(tree)
+	tests := []struct {
+		name             string
+		ascendancy       string
+		allocated        []int
+		targets          []int
+		nodes            []int
+		wantAscendancy   string
+		points           int
+		ascendancyPoints int
+	}{
+		{"keystone", "", nil, []int{104}, []int{101, 102, 103, 104}, "", 4, 0},
+		{"already allocated", "", []int{101, 102}, []int{103}, []int{101, 102, 103}, "", 3, 0},
+		{"mastery through its group", "", nil, []int{110}, []int{101, 110}, "", 2, 0},
+		{"ascendancy target without an ascendancy", "", nil, []int{102, 201}, []int{101, 102, 201}, "Occultist", 2, 1},
+		{"chosen ascendancy", "Elementalist", nil, []int{301}, []int{301}, "Elementalist", 0, 1},
+	}
+	for _, test := range tests {
+		plan, err := graph.PlanAllocation(1, test.ascendancy, test.allocated, test.targets)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !slices.Equal(plan.Nodes, test.nodes) || plan.Ascendancy != test.wantAscendancy ||
+			plan.Points != test.points || plan.AscendancyPoints != test.ascendancyPoints {
+			t.Errorf("%s: planned %v in %q for %d+%d points, want %v in %q for %d+%d", test.name,
+				plan.Nodes, plan.Ascendancy, plan.Points, plan.AscendancyPoints,
+				test.nodes, test.wantAscendancy, test.points, test.ascendancyPoints)
+		}
+		if plan.TotalPoints != 123 || plan.TotalAscendancyPoints != 8 || plan.ExceedsPoints() {
+			t.Errorf("%s: available points %d+%d", test.name, plan.TotalPoints, plan.TotalAscendancyPoints)
+		}
+	}
+}
+
+func TestPlanAllocationErrors(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := NewGraph(tree)
+	tests := []struct {
+		name       string
+		ascendancy string
+		targets    []int
+		err        string
+	}{
+		{"two ascendancies", "", []int{201, 301}, "both the Occultist and Elementalist"},
+		{"other ascendancy", "Occultist", []int{301}, "not reachable"},
+		{"isolated node", "", []int{500}, "not reachable"},
+	}
+	for _, test := range tests {
+		_, err := graph.PlanAllocation(1, test.ascendancy, nil, test.targets)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
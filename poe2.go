@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Game identifies which game a tree belongs to, the games differ in their
+// export schema, orbit constants and ascendancy layout
+type Game int
+
+const (
+	PoE1 Game = iota
+	PoE2
+)
+
+// path of exile 2 exports list the connections of a node together with the
+// orbit of the arc connecting them, 0 for a straight line and negative for
+// arcs bending the other way
+type poe2Connection struct {
+	Id    legacyId `json:"id"`
+	Orbit int      `json:"orbit"`
+}
+
+type poe2Node struct {
+	Skill                int              `json:"skill"`
+	Name                 string           `json:"name"`
+	Icon                 string           `json:"icon"`
+	Stats                []string         `json:"stats"`
+	ReminderText         []string         `json:"reminderText"`
+	FlavourText          []string         `json:"flavourText"`
+	Group                int              `json:"group"`
+	Orbit                int              `json:"orbit"`
+	OrbitIndex           int              `json:"orbitIndex"`
+	Connections          []poe2Connection `json:"connections"`
+	IsNotable            bool             `json:"isNotable"`
+	IsKeystone           bool             `json:"isKeystone"`
+	IsJewelSocket        bool             `json:"isJewelSocket"`
+	IsAttribute          bool             `json:"isAttribute"`
+	IsAscendancyStart    bool             `json:"isAscendancyStart"`
+	IsMultipleChoice     bool             `json:"isMultipleChoice"`
+	AscendancyName       string           `json:"ascendancyName"`
+	ClassesStart         []string         `json:"classesStart"`
+	GrantedPassivePoints int              `json:"grantedPassivePoints"`
+	// weapon set passives can only be allocated with weapon set points
+	IsWeaponSet bool `json:"isWeaponSet"`
+}
+
+type poe2Group struct {
+	X      float64    `json:"x"`
+	Y      float64    `json:"y"`
+	Orbits []int      `json:"orbits"`
+	Nodes  []legacyId `json:"nodes"`
+}
+
+type poe2Tree struct {
+	Tree      string               `json:"tree"`
+	Classes   []Classes            `json:"classes"`
+	Groups    map[string]poe2Group `json:"groups"`
+	Nodes     map[string]poe2Node  `json:"nodes"`
+	MinX      int                  `json:"min_x"`
+	MinY      int                  `json:"min_y"`
+	MaxX      int                  `json:"max_x"`
+	MaxY      int                  `json:"max_y"`
+	Constants Constants            `json:"constants"`
+	Points    PassivePoints        `json:"points"`
+}
+
+func DecodePoE2Tree(data []byte) (Tree, error) {
+	export := poe2Tree{}
+	err := json.Unmarshal(data, &export)
+	if err != nil {
+		return Tree{}, err
+	}
+	tree := Tree{
+		Tree:      export.Tree,
+		Game:      PoE2,
+		Classes:   export.Classes,
+		Groups:    make(map[string]Group, len(export.Groups)),
+		Nodes:     make(map[string]Node, len(export.Nodes)),
+		MinX:      export.MinX,
+		MinY:      export.MinY,
+		MaxX:      export.MaxX,
+		MaxY:      export.MaxY,
+		Constants: export.Constants,
+		Points:    export.Points,
+	}
+	for groupId, group := range export.Groups {
+		for _, orbit := range group.Orbits {
+			if orbit < 0 || orbit >= len(export.Constants.OrbitRadii) {
+				return Tree{}, fmt.Errorf("group %s: unknown orbit %d", groupId, orbit)
+			}
+		}
+		tree.Groups[groupId] = Group{
+			X:      group.X,
+			Y:      group.Y,
+			Orbits: group.Orbits,
+			Nodes:  legacyIds(group.Nodes),
+		}
+	}
+
+	classIndices := make(map[string]int, len(export.Classes))
+	for i, class := range export.Classes {
+		classIndices[class.Name] = i
+	}
+	for nodeid, exportNode := range export.Nodes {
+		err := exportNode.CheckOrbits(export.Constants)
+		if err != nil {
+			return Tree{}, fmt.Errorf("node %s: %w", nodeid, err)
+		}
+		node, err := exportNode.Convert(classIndices)
+		if err != nil {
+			return Tree{}, fmt.Errorf("node %s: %w", nodeid, err)
+		}
+		if node.Skill == 0 {
+			node.Skill, _ = strconv.Atoi(nodeid)
+		}
+		tree.Nodes[nodeid] = node
+	}
+	fillIncomingConnections(&tree)
+	return tree, nil
+}
+
+// the orbits are looked up in the constants when drawing, the nodes and the
+// arcs of their connections have to use orbits the export defines
+func (n poe2Node) CheckOrbits(constants Constants) error {
+	orbits := min(len(constants.OrbitRadii), len(constants.SkillsPerOrbit))
+	if n.Orbit < 0 || n.Orbit >= orbits {
+		return fmt.Errorf("unknown orbit %d", n.Orbit)
+	}
+	if n.OrbitIndex < 0 || n.OrbitIndex >= constants.SkillsPerOrbit[n.Orbit] {
+		return fmt.Errorf("orbit index %d out of range for orbit %d", n.OrbitIndex, n.Orbit)
+	}
+	for _, connection := range n.Connections {
+		if max(connection.Orbit, -connection.Orbit) >= len(constants.OrbitRadii) {
+			return fmt.Errorf("unknown orbit %d for the connection to %s", connection.Orbit, connection.Id)
+		}
+	}
+	return nil
+}
+
+func (n poe2Node) Convert(classIndices map[string]int) (Node, error) {
+	node := Node{
+		Group:                n.Group,
+		Orbit:                n.Orbit,
+		OrbitIndex:           n.OrbitIndex,
+		Out:                  make([]string, 0, len(n.Connections)),
+		Skill:                n.Skill,
+		Stats:                n.Stats,
+		ReminderText:         n.ReminderText,
+		FlavourText:          n.FlavourText,
+		GrantedPassivePoints: n.GrantedPassivePoints,
+		IsNotable:            n.IsNotable,
+		IsKeystone:           n.IsKeystone,
+		IsJewelSocket:        n.IsJewelSocket,
+		IsAttribute:          n.IsAttribute,
+		IsWeaponSet:          n.IsWeaponSet,
+		IsAscendancyStart:    n.IsAscendancyStart,
+		IsMultipleChoice:     n.IsMultipleChoice,
+	}
+	for _, connection := range n.Connections {
+		neighbourId := string(connection.Id)
+		node.Out = append(node.Out, neighbourId)
+		if connection.Orbit != 0 {
+			if node.ConnectionOrbits == nil {
+				node.ConnectionOrbits = make(map[string]int)
+			}
+			node.ConnectionOrbits[neighbourId] = connection.Orbit
+		}
+	}
+	if n.Name != "" {
+		name := n.Name
+		node.Name = &name
+	}
+	if n.Icon != "" {
+		icon := n.Icon
+		node.Icon = &icon
+	}
+	if n.AscendancyName != "" {
+		ascendancyName := n.AscendancyName
+		node.AscendancyName = &ascendancyName
+	}
+	// class starts are shared by two classes, the first one is used
+	if len(n.ClassesStart) > 0 {
+		classStartIndex, exists := classIndices[n.ClassesStart[0]]
+		if !exists {
+			return Node{}, fmt.Errorf("unknown class %q", n.ClassesStart[0])
+		}
+		node.ClassStartIndex = &classStartIndex
+	}
+	return node, nil
+}
+
+// returns the angle of a node on its orbit, path of exile 2 spaces all
+// orbits evenly while path of exile 1 uses fixed angles for some orbits
+func (t Tree) OrbitAngle(orbit int, index int) float64 {
+	total := t.Constants.SkillsPerOrbit[orbit]
+	if t.Game == PoE2 {
+		return 2 * math.Pi * float64(index) / float64(total)
+	}
+	return GetOrbitAngle(index, total)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const poe2TreeExport = `{
+	"tree": "Default",
+	"classes": [
+		{"name": "Warrior", "ascendancies": [{"id": "Warrior1", "name": "Titan"}]},
+		{"name": "Marauder", "ascendancies": []}
+	],
+	"groups": {
+		"1": {"x": 0, "y": 0, "orbits": [0], "nodes": [1]},
+		"2": {"x": 400, "y": 0, "orbits": [2], "nodes": [10, 11]},
+		"4": {"x": -3000, "y": -3000, "orbits": [0], "nodes": [20]},
+		"5": {"x": -2800, "y": -3000, "orbits": [2], "nodes": [21]},
+		"6": {"x": 5000, "y": 5000, "orbits": [0], "nodes": [30]}
+	},
+	"nodes": {
+		"1": {"skill": 1, "name": "WARRIOR", "group": 1, "orbit": 0, "orbitIndex": 0, "connections": [{"id": 10, "orbit": 0}], "classesStart": ["Warrior", "Marauder"]},
+		"10": {"skill": 10, "name": "Strength", "group": 2, "orbit": 2, "orbitIndex": 0, "connections": [{"id": 11, "orbit": -2}], "isAttribute": true},
+		"11": {"skill": 11, "name": "Swap Damage", "group": 2, "orbit": 2, "orbitIndex": 6, "connections": [], "isWeaponSet": true},
+		"20": {"skill": 20, "name": "Titan", "group": 4, "orbit": 0, "orbitIndex": 0, "connections": [{"id": 21, "orbit": 0}], "isAscendancyStart": true, "ascendancyName": "Titan"},
+		"21": {"skill": 21, "name": "Stone Skin", "group": 5, "orbit": 2, "orbitIndex": 4, "connections": [], "isNotable": true, "ascendancyName": "Titan"},
+		"30": {"skill": 30, "name": "Unfinished", "group": 6, "orbit": 0, "orbitIndex": 0, "connections": [], "ascendancyName": "Unfinished"}
+	},
+	"min_x": -4000, "min_y": -4000, "max_x": 6000, "max_y": 6000,
+	"constants": {"skillsPerOrbit": [1, 12, 24], "orbitRadii": [0, 82, 162]},
+	"points": {"totalPoints": 123, "ascendancyPoints": 8, "weaponSetPoints": 24}
+}`
+
+func TestDecodePoE2Tree(t *testing.T) {
+	tree, err := DecodeTree([]byte(poe2TreeExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Game != PoE2 || tree.Points.WeaponSetPoints != 24 {
+		t.Errorf("game %d, points %+v", tree.Game, tree.Points)
+	}
+	// class starts shared by two classes belong to the first one
+	start := tree.Nodes["1"]
+	if start.ClassStartIndex == nil || *start.ClassStartIndex != 0 {
+		t.Errorf("class start = %+v", start)
+	}
+	attribute := tree.Nodes["10"]
+	if !attribute.IsAttribute || !slices.Equal(attribute.Out, []string{"11"}) || attribute.ConnectionOrbits["11"] != -2 {
+		t.Errorf("attribute = %+v", attribute)
+	}
+	if weaponSet := tree.Nodes["11"]; !weaponSet.IsWeaponSet || !slices.Equal(weaponSet.In, []string{"10"}) {
+		t.Errorf("weapon set passive = %+v", weaponSet)
+	}
+	if ascendancy := tree.Nodes["20"]; ascendancy.AscendancyName == nil || *ascendancy.AscendancyName != "Titan" || !ascendancy.IsAscendancyStart {
+		t.Errorf("ascendancy start = %+v", ascendancy)
+	}
+}
+
+func TestDecodePoE2TreeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		err  string
+	}{
+		{"node orbit", `"group": 2, "orbit": 2, "orbitIndex": 0`, `"group": 2, "orbit": 3, "orbitIndex": 0`, "node 10: unknown orbit 3"},
+		{"orbit index", `"orbit": 2, "orbitIndex": 6`, `"orbit": 2, "orbitIndex": 24`, "node 11: orbit index 24 out of range"},
+		{"connection orbit", `{"id": 11, "orbit": -2}`, `{"id": 11, "orbit": -3}`, "unknown orbit -3 for the connection to 11"},
+		{"group orbit", `"orbits": [2], "nodes": [10, 11]`, `"orbits": [7], "nodes": [10, 11]`, "group 2: unknown orbit 7"},
+		{"class", `"classesStart": ["Warrior", "Marauder"]`, `"classesStart": ["Monk"]`, "unknown class"},
+	}
+	for _, test := range tests {
+		data := strings.Replace(poe2TreeExport, test.old, test.new, 1)
+		if data == poe2TreeExport {
+			t.Fatalf("%s: %q is not in the export", test.name, test.old)
+		}
+		_, err := DecodeTree([]byte(data))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestOrbitAngle(t *testing.T) {
+	tests := []struct {
+		game  Game
+		orbit int
+		index int
+		angle float64
+	}{
+		{PoE2, 2, 6, 3 * math.Pi / 4},
+		{PoE2, 1, 3, math.Pi / 2},
+		{PoE1, 2, 4, math.Pi / 2},
+		{PoE1, 2, 2, math.Pi / 4},
+	}
+	for _, test := range tests {
+		tree := Tree{Game: test.game, Constants: Constants{SkillsPerOrbit: []int{1, 12, 16}}}
+		angle := tree.OrbitAngle(test.orbit, test.index)
+		if math.Abs(angle-test.angle) > 1e-9 {
+			t.Errorf("game %d OrbitAngle(%d, %d) = %f, want %f", test.game, test.orbit, test.index, angle, test.angle)
+		}
+	}
+}
+
+func TestMovePoE2AscendancyTrees(t *testing.T) {
+	tree, err := DecodeTree([]byte(poe2TreeExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	MoveAscendancyTrees(&tree)
+	// the bounds only cover the passive tree, the ascendancy start is moved
+	// into their top right corner as for path of exile 1 and the rest of the
+	// ascendancy keeps its offset
+	if tree.MinX != -200 || tree.MaxX != 762 || tree.MinY != -362 || tree.MaxY != 200 {
+		t.Errorf("bounds %d, %d, %d, %d", tree.MinX, tree.MinY, tree.MaxX, tree.MaxY)
+	}
+	x, y := float64(tree.MaxX-1000), float64(-tree.MaxY+1000)
+	if group := tree.Groups["4"]; group.X != x || group.Y != y {
+		t.Errorf("start group at %f, %f, want %f, %f", group.X, group.Y, x, y)
+	}
+	if group := tree.Groups["5"]; group.X != x+200 || group.Y != y {
+		t.Errorf("ascendancy group at %f, %f, want %f, %f", group.X, group.Y, x+200, y)
+	}
+	// an ascendancy without a start node is left in place
+	if group := tree.Groups["6"]; group.X != 5000 || group.Y != 5000 {
+		t.Errorf("group without a start moved to %f, %f", group.X, group.Y)
+	}
+}
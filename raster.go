@@ -0,0 +1,547 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type RasterOptions struct {
+	Width      int
+	Background color.RGBA
+}
+
+// Shape is a circle, line or arc of an svg with its computed colors, lines
+// and arcs are flattened into points in tree coordinates
+type Shape struct {
+	Circle      bool
+	Points      [][2]float64
+	Radius      float64
+	Fill        color.RGBA
+	HasFill     bool
+	Stroke      color.RGBA
+	HasStroke   bool
+	StrokeWidth float64
+	Ascendancy  string
+}
+
+// Text is a text element of an svg, which only vector outputs paint
+type Text struct {
+	X          float64
+	Y          float64
+	Content    string
+	FontSize   float64
+	Anchor     string
+	Baseline   string
+	Fill       color.RGBA
+	HasFill    bool
+	Ascendancy string
+}
+
+type Scene struct {
+	ViewBox [4]float64
+	Shapes  []Shape
+	Texts   []Text
+}
+
+// ParseScene reads the shapes and texts of an svg written by TreeDrawer, the
+// patterns in defs and the tooltips are skipped
+func ParseScene(data []byte) (Scene, error) {
+	scene := Scene{Shapes: make([]Shape, 0), Texts: make([]Text, 0)}
+	viewBox, err := parseViewBox(data)
+	if err != nil {
+		return scene, err
+	}
+	scene.ViewBox = viewBox
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var rules []StyleRule
+	style := ""
+	inStyle := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return scene, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			attrs := make(map[string]string, len(t.Attr))
+			for _, attr := range t.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			// the style is written before any shape or text
+			if rules == nil && (isShape(t.Name.Local) || t.Name.Local == "text") {
+				if style == "" {
+					style = DefaultStyle
+				}
+				rules = ParseStyle(style)
+			}
+			switch t.Name.Local {
+			case "style":
+				inStyle = true
+			case "pattern", "title":
+				// patterns and tooltips are not painted
+				err = decoder.Skip()
+				if err != nil {
+					return scene, err
+				}
+			case "text":
+				var content struct {
+					Text string `xml:",chardata"`
+				}
+				err = decoder.DecodeElement(&content, &t)
+				if err != nil {
+					return scene, err
+				}
+				text, err := NewText(rules, attrs, content.Text)
+				if err != nil {
+					return scene, err
+				}
+				scene.Texts = append(scene.Texts, text)
+			case "circle", "line", "path":
+				shape, err := NewShape(rules, t.Name.Local, attrs)
+				if err != nil {
+					return scene, err
+				}
+				scene.Shapes = append(scene.Shapes, shape)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "style" {
+				inStyle = false
+			}
+		case xml.CharData:
+			if inStyle {
+				style += string(t)
+			}
+		}
+	}
+	return scene, nil
+}
+
+func isShape(tag string) bool {
+	return tag == "circle" || tag == "line" || tag == "path"
+}
+
+// the ascendancy an element belongs to, written first in its data-extras
+func elementAscendancy(attrs map[string]string) string {
+	if !slices.Contains(strings.Fields(attrs["class"]), "ascendancy") {
+		return ""
+	}
+	ascendancy, _, _ := strings.Cut(html.UnescapeString(attrs["data-extras"]), ",")
+	return ascendancy
+}
+
+func parseViewBox(data []byte) ([4]float64, error) {
+	viewBox := [4]float64{}
+	_, rest, found := bytes.Cut(data, []byte(`viewBox="`))
+	if !found {
+		return viewBox, fmt.Errorf("svg has no viewBox")
+	}
+	value, _, _ := bytes.Cut(rest, []byte(`"`))
+	fields := strings.Fields(string(value))
+	if len(fields) != 4 {
+		return viewBox, fmt.Errorf("invalid viewBox %q", value)
+	}
+	for i, field := range fields {
+		number, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return viewBox, fmt.Errorf("invalid viewBox %q", value)
+		}
+		viewBox[i] = number
+	}
+	if viewBox[2] <= 0 || viewBox[3] <= 0 {
+		return viewBox, fmt.Errorf("invalid viewBox %q", value)
+	}
+	return viewBox, nil
+}
+
+func attrFloat(attrs map[string]string, name string) float64 {
+	value, _ := strconv.ParseFloat(attrs[name], 64)
+	return value
+}
+
+func NewShape(rules []StyleRule, tag string, attrs map[string]string) (Shape, error) {
+	shape := Shape{Ascendancy: elementAscendancy(attrs)}
+	style := ComputedStyle(rules, tag, strings.Fields(attrs["class"]), attrs["style"])
+	if presentation, exists := attrs["fill"]; exists {
+		if _, styled := style["fill"]; !styled {
+			// presentation attributes are checked like declarations
+			maps.Copy(style, ParseDeclarations("fill:"+presentation))
+		}
+	}
+	var err error
+	shape.Fill, shape.HasFill, err = ParseColor(style["fill"])
+	if err != nil {
+		return shape, err
+	}
+	shape.Stroke, shape.HasStroke, err = ParseColor(style["stroke"])
+	if err != nil {
+		return shape, err
+	}
+	shape.StrokeWidth, err = strconv.ParseFloat(strings.TrimSuffix(style["stroke-width"], "px"), 64)
+	if err != nil {
+		shape.StrokeWidth = 1
+	}
+
+	switch tag {
+	case "circle":
+		shape.Circle = true
+		shape.Points = [][2]float64{{attrFloat(attrs, "cx"), attrFloat(attrs, "cy")}}
+		shape.Radius = attrFloat(attrs, "r")
+	case "line":
+		shape.HasFill = false
+		shape.Points = [][2]float64{{attrFloat(attrs, "x1"), attrFloat(attrs, "y1")}, {attrFloat(attrs, "x2"), attrFloat(attrs, "y2")}}
+	case "path":
+		shape.HasFill = false
+		shape.Points, err = pathPoints(attrs["d"])
+		if err != nil {
+			return shape, err
+		}
+	}
+	return shape, nil
+}
+
+// Rasterizer paints shapes onto an image, mapping tree coordinates from
+// minX, minY onwards to pixels with the given scale
+type Rasterizer struct {
+	img   *image.RGBA
+	minX  float64
+	minY  float64
+	scale float64
+}
+
+func NewRasterizer(minX, minY, scale float64, width, height int, background color.RGBA) *Rasterizer {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = background.R, background.G, background.B, 0xff
+	}
+	return &Rasterizer{img: img, minX: minX, minY: minY, scale: scale}
+}
+
+// RasterizeScene paints the whole view box of a scene at the given width
+func RasterizeScene(scene Scene, options RasterOptions) (*image.RGBA, error) {
+	if options.Width <= 0 {
+		return nil, fmt.Errorf("invalid width %d, must be greater than 0", options.Width)
+	}
+	scale := float64(options.Width) / scene.ViewBox[2]
+	height := int(math.Ceil(scene.ViewBox[3] * scale))
+	r := NewRasterizer(scene.ViewBox[0], scene.ViewBox[1], scale, options.Width, height, options.Background)
+	r.PaintAll(scene.Shapes)
+	return r.img, nil
+}
+
+func (r *Rasterizer) PaintAll(shapes []Shape) {
+	for _, shape := range shapes {
+		r.Paint(shape)
+	}
+}
+
+func (r *Rasterizer) Paint(shape Shape) {
+	// thin strokes would disappear when scaled down
+	width := max(shape.StrokeWidth*r.scale, 1)
+	points := make([][2]float64, len(shape.Points))
+	for i, p := range shape.Points {
+		points[i][0], points[i][1] = r.point(p[0], p[1])
+	}
+	if shape.Circle {
+		radius := max(shape.Radius*r.scale, 0.5)
+		if shape.HasFill {
+			r.fillCircle(points[0][0], points[0][1], radius, shape.Fill)
+		}
+		if shape.HasStroke {
+			r.strokeCircle(points[0][0], points[0][1], radius, width, shape.Stroke)
+		}
+		return
+	}
+	if shape.HasStroke {
+		r.strokePolyline(points, width, shape.Stroke)
+	}
+}
+
+func NewText(rules []StyleRule, attrs map[string]string, content string) (Text, error) {
+	style := ComputedStyle(rules, "text", strings.Fields(attrs["class"]), attrs["style"])
+	text := Text{
+		X:          attrFloat(attrs, "x"),
+		Y:          attrFloat(attrs, "y"),
+		Content:    strings.TrimSpace(content),
+		FontSize:   16,
+		Anchor:     attrs["text-anchor"],
+		Baseline:   attrs["dominant-baseline"],
+		Ascendancy: elementAscendancy(attrs),
+	}
+	// the style overrides the font-size attribute, as in browsers
+	fontSize := attrs["font-size"]
+	if value, exists := style["font-size"]; exists {
+		fontSize = value
+	}
+	if size, err := strconv.ParseFloat(strings.TrimSuffix(fontSize, "px"), 64); err == nil {
+		text.FontSize = size
+	}
+	fill, exists := style["fill"]
+	if !exists {
+		fill = "#000000"
+	}
+	var err error
+	text.Fill, text.HasFill, err = ParseColor(fill)
+	return text, err
+}
+
+func (r *Rasterizer) point(x, y float64) (float64, float64) {
+	return (x - r.minX) * r.scale, (y - r.minY) * r.scale
+}
+
+// blends a color onto a pixel with the given coverage
+func (r *Rasterizer) blend(x, y int, c color.RGBA, coverage float64) {
+	if coverage <= 0 || !(image.Point{x, y}.In(r.img.Rect)) {
+		return
+	}
+	coverage = min(coverage, 1)
+	// the colors are premultiplied, translucent ones cover less of the pixel
+	alpha := coverage * float64(c.A) / 0xff
+	i := r.img.PixOffset(x, y)
+	pix := r.img.Pix[i : i+3 : i+3]
+	pix[0] = uint8(float64(pix[0])*(1-alpha) + float64(c.R)*coverage)
+	pix[1] = uint8(float64(pix[1])*(1-alpha) + float64(c.G)*coverage)
+	pix[2] = uint8(float64(pix[2])*(1-alpha) + float64(c.B)*coverage)
+}
+
+// calls paint for every pixel center within the bounds, shapes are anti
+// aliased by their distance to the pixel centers
+func (r *Rasterizer) each(minX, minY, maxX, maxY float64, paint func(px, py float64) float64, c color.RGBA) {
+	bounds := r.img.Rect
+	x0, y0 := max(int(math.Floor(minX)), bounds.Min.X), max(int(math.Floor(minY)), bounds.Min.Y)
+	x1, y1 := min(int(math.Ceil(maxX)), bounds.Max.X-1), min(int(math.Ceil(maxY)), bounds.Max.Y-1)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			r.blend(x, y, c, paint(float64(x)+0.5, float64(y)+0.5))
+		}
+	}
+}
+
+func (r *Rasterizer) fillCircle(cx, cy, radius float64, c color.RGBA) {
+	r.each(cx-radius-1, cy-radius-1, cx+radius+1, cy+radius+1, func(px, py float64) float64 {
+		return 0.5 - (math.Hypot(px-cx, py-cy) - radius)
+	}, c)
+}
+
+func (r *Rasterizer) strokeCircle(cx, cy, radius, width float64, c color.RGBA) {
+	outer := radius + width/2 + 1
+	r.each(cx-outer, cy-outer, cx+outer, cy+outer, func(px, py float64) float64 {
+		return 0.5 - (math.Abs(math.Hypot(px-cx, py-cy)-radius) - width/2)
+	}, c)
+}
+
+func (r *Rasterizer) strokePolyline(points [][2]float64, width float64, c color.RGBA) {
+	if len(points) < 2 {
+		return
+	}
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, minY = min(minX, p[0]), min(minY, p[1])
+		maxX, maxY = max(maxX, p[0]), max(maxY, p[1])
+	}
+	pad := width/2 + 1
+	r.each(minX-pad, minY-pad, maxX+pad, maxY+pad, func(px, py float64) float64 {
+		distance := math.Inf(1)
+		for i := 1; i < len(points); i++ {
+			distance = min(distance, segmentDistance(px, py, points[i-1], points[i]))
+		}
+		return 0.5 - (distance - width/2)
+	}, c)
+}
+
+func segmentDistance(px, py float64, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	length := dx*dx + dy*dy
+	t := 0.0
+	if length > 0 {
+		t = max(0, min(1, ((px-a[0])*dx+(py-a[1])*dy)/length))
+	}
+	return math.Hypot(px-(a[0]+t*dx), py-(a[1]+t*dy))
+}
+
+// flattens the absolute move, line and arc commands of a path into points
+func pathPoints(d string) ([][2]float64, error) {
+	var b strings.Builder
+	for _, r := range d {
+		switch {
+		case r == ',':
+			b.WriteRune(' ')
+		case strings.ContainsRune("MLA", r):
+			b.WriteString(" " + string(r) + " ")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	fields := strings.Fields(b.String())
+	numbers := func(i, n int) ([]float64, error) {
+		if i+n > len(fields) {
+			return nil, fmt.Errorf("invalid path %q", d)
+		}
+		values := make([]float64, n)
+		for j := range values {
+			value, err := strconv.ParseFloat(fields[i+j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q", d)
+			}
+			values[j] = value
+		}
+		return values, nil
+	}
+	points := make([][2]float64, 0)
+	for i := 0; i < len(fields); {
+		command := fields[i]
+		i++
+		switch command {
+		case "M", "L":
+			values, err := numbers(i, 2)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, [2]float64{values[0], values[1]})
+			i += 2
+		case "A":
+			values, err := numbers(i, 7)
+			if err != nil {
+				return nil, err
+			}
+			if len(points) == 0 {
+				return nil, fmt.Errorf("invalid path %q", d)
+			}
+			points = append(points, arcPoints(points[len(points)-1], values)...)
+			i += 7
+		default:
+			return nil, fmt.Errorf("unsupported path command %q", command)
+		}
+	}
+	return points, nil
+}
+
+// converts an svg arc from endpoint to center parameterization and samples
+// it, following the svg implementation notes
+func arcPoints(start [2]float64, values []float64) [][2]float64 {
+	rx, ry := math.Abs(values[0]), math.Abs(values[1])
+	largeArc, sweep := values[3] != 0, values[4] != 0
+	end := [2]float64{values[5], values[6]}
+	if rx == 0 || ry == 0 {
+		return [][2]float64{end}
+	}
+	// rotation is not used by the drawers and the arcs are circular
+	dx, dy := (start[0]-end[0])/2, (start[1]-end[1])/2
+	lambda := (dx*dx)/(rx*rx) + (dy*dy)/(ry*ry)
+	if lambda > 1 {
+		rx, ry = rx*math.Sqrt(lambda), ry*math.Sqrt(lambda)
+	}
+	numerator := rx*rx*ry*ry - rx*rx*dy*dy - ry*ry*dx*dx
+	denominator := rx*rx*dy*dy + ry*ry*dx*dx
+	factor := math.Sqrt(max(0, numerator/denominator))
+	if largeArc == sweep {
+		factor = -factor
+	}
+	cxp, cyp := factor*rx*dy/ry, -factor*ry*dx/rx
+	cx, cy := cxp+(start[0]+end[0])/2, cyp+(start[1]+end[1])/2
+
+	theta1 := math.Atan2((dy-cyp)/ry, (dx-cxp)/rx)
+	theta2 := math.Atan2((-dy-cyp)/ry, (-dx-cxp)/rx)
+	delta := theta2 - theta1
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	steps := max(4, int(math.Abs(delta)*max(rx, ry)/10))
+	points := make([][2]float64, 0, steps)
+	for i := 1; i <= steps; i++ {
+		angle := theta1 + delta*float64(i)/float64(steps)
+		points = append(points, [2]float64{cx + rx*math.Cos(angle), cy + ry*math.Sin(angle)})
+	}
+	points[len(points)-1] = end
+	return points
+}
+
+func WritePng(w io.Writer, tree Tree, options DrawOptions, rasterOptions RasterOptions) error {
+	options = options.ForRaster()
+	var svgBuffer bytes.Buffer
+	WriteSvg(&svgBuffer, tree, options)
+	scene, err := ParseScene(svgBuffer.Bytes())
+	if err != nil {
+		return err
+	}
+	img, err := RasterizeScene(scene, rasterOptions)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+func (c Config) PngPath(file TreeFile) string {
+	return filepath.Join(c.OutputDir, "png", file.Kind.Name, file.Version+".png")
+}
+
+func RunPng(args []string) error {
+	fs, config := NewFlagSet("png")
+	drawOptions := AddDrawFlags(fs)
+	width := fs.Int("width", 2048, "width of the images in pixels")
+	background := fs.String("background", "", "background color of the images (default the background of the theme)")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	options, err := drawOptions()
+	if err != nil {
+		return err
+	}
+	if *width <= 0 {
+		return fmt.Errorf("invalid width %d, must be greater than 0", *width)
+	}
+	backgroundColor, err := RasterBackground(*background, options.ForRaster())
+	if err != nil {
+		return err
+	}
+	rasterOptions := RasterOptions{Width: *width, Background: backgroundColor}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return err
+	}
+	err = MakeOutputDirs(cfg, "png")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Generating PNG for %s %s\n", file.Kind.Name, file.Version)
+		tree, err := LoadTree(file.Path)
+		if err != nil {
+			return err
+		}
+		outFile, err := os.Create(cfg.PngPath(file))
+		if err != nil {
+			return err
+		}
+		err = WritePng(outFile, tree, options, rasterOptions)
+		outFile.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
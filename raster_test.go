@@ -0,0 +1,173 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func nearPoint(a, b [2]float64) bool {
+	return math.Abs(a[0]-b[0]) < 1e-6 && math.Abs(a[1]-b[1]) < 1e-6
+}
+
+func TestPathPoints(t *testing.T) {
+	points, err := pathPoints("M0,0 L10,0L10 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]float64{{0, 0}, {10, 0}, {10, 10}}
+	if len(points) != len(want) {
+		t.Fatalf("pathPoints = %v, want %v", points, want)
+	}
+	for i := range want {
+		if !nearPoint(points[i], want[i]) {
+			t.Errorf("point %d = %v, want %v", i, points[i], want[i])
+		}
+	}
+
+	for _, d := range []string{"M0", "L10,0 Q1,1", "A10,10,0,0,1,10,0", "M0,0 Lx,1"} {
+		if _, err := pathPoints(d); err == nil {
+			t.Errorf("pathPoints(%q) should fail", d)
+		}
+	}
+}
+
+func TestArcPoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		middle [2]float64
+	}{
+		// half circles of radius 10 from (-10, 0) to (10, 0), y points down
+		{"sweep", []float64{10, 10, 0, 0, 1, 10, 0}, [2]float64{0, -10}},
+		{"no sweep", []float64{10, 10, 0, 0, 0, 10, 0}, [2]float64{0, 10}},
+	}
+	for _, test := range tests {
+		points := arcPoints([2]float64{-10, 0}, test.values)
+		if len(points)%2 != 0 {
+			t.Fatalf("%s: got %d points, want an even number", test.name, len(points))
+		}
+		if end := points[len(points)-1]; end != [2]float64{10, 0} {
+			t.Errorf("%s: ends at %v", test.name, end)
+		}
+		if middle := points[len(points)/2-1]; !nearPoint(middle, test.middle) {
+			t.Errorf("%s: passes %v, want %v", test.name, middle, test.middle)
+		}
+		for _, p := range points {
+			if r := math.Hypot(p[0], p[1]); math.Abs(r-10) > 1e-6 {
+				t.Errorf("%s: point %v is not on the circle", test.name, p)
+			}
+		}
+	}
+	// a radius too small to reach the end is scaled up
+	points := arcPoints([2]float64{-10, 0}, []float64{1, 1, 0, 0, 1, 10, 0})
+	if !nearPoint(points[len(points)/2-1], [2]float64{0, -10}) {
+		t.Errorf("scaled arc passes %v", points[len(points)/2-1])
+	}
+	// zero radii draw a straight line
+	if points := arcPoints([2]float64{0, 0}, []float64{0, 0, 0, 0, 1, 5, 5}); len(points) != 1 || points[0] != [2]float64{5, 5} {
+		t.Errorf("zero radius arc = %v", points)
+	}
+}
+
+const rasterSvg = `<?xml version="1.0"?>
+<svg width="200" height="100" viewBox="-100 -50 200 100" xmlns="http://www.w3.org/2000/svg">
+<defs>
+<style>
+circle { fill: #ff0000; stroke: none; }
+circle.keystone { fill: #00ff00; }
+line, path { stroke: #0000ff; stroke-width: 4px; }
+</style>
+<pattern id="icon"><circle cx="0" cy="0" r="1000" /></pattern>
+</defs>
+<circle cx="-50" cy="0" r="10" class="keystone ascendancy" data-extras="Occultist,x"><title>Elemental Equilibrium</title></circle>
+<circle cx="50" cy="0" r="10" style="fill: #ffffff" />
+<line x1="-90" y1="-40" x2="90" y2="-40" class="connection" />
+<path d="M-90,40 A180,180 0 0 1 90,40" class="connection" />
+<text x="0" y="0" font-size="20" class="class-name">WITCH</text>
+</svg>`
+
+func TestParseScene(t *testing.T) {
+	scene, err := ParseScene([]byte(rasterSvg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scene.ViewBox != [4]float64{-100, -50, 200, 100} {
+		t.Errorf("view box = %v", scene.ViewBox)
+	}
+	// the circle in the pattern is not a shape of the scene
+	if len(scene.Shapes) != 4 {
+		t.Fatalf("parsed %d shapes, want 4", len(scene.Shapes))
+	}
+	keystone := scene.Shapes[0]
+	if !keystone.Circle || keystone.Radius != 10 || keystone.Fill != (color.RGBA{0, 0xff, 0, 0xff}) || keystone.HasStroke || keystone.Ascendancy != "Occultist" {
+		t.Errorf("keystone = %+v", keystone)
+	}
+	if inline := scene.Shapes[1]; inline.Fill != (color.RGBA{0xff, 0xff, 0xff, 0xff}) || inline.Ascendancy != "" {
+		t.Errorf("inline styled circle = %+v", inline)
+	}
+	line := scene.Shapes[2]
+	if line.Circle || line.HasFill || line.Stroke != (color.RGBA{0, 0, 0xff, 0xff}) || line.StrokeWidth != 4 || len(line.Points) != 2 {
+		t.Errorf("line = %+v", line)
+	}
+	if arc := scene.Shapes[3]; arc.HasFill || len(arc.Points) < 3 || arc.Points[len(arc.Points)-1] != [2]float64{90, 40} {
+		t.Errorf("arc = %+v", arc)
+	}
+	if len(scene.Texts) != 1 || scene.Texts[0].Content != "WITCH" || scene.Texts[0].FontSize != 20 {
+		t.Errorf("texts = %+v", scene.Texts)
+	}
+
+	for _, data := range []string{`<svg width="10"></svg>`, `<svg viewBox="0 0 10"></svg>`, `<svg viewBox="0 0 0 10"></svg>`} {
+		if _, err := ParseScene([]byte(data)); err == nil {
+			t.Errorf("ParseScene(%q) should fail", data)
+		}
+	}
+}
+
+func TestRasterizeScene(t *testing.T) {
+	scene, err := ParseScene([]byte(rasterSvg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	background := color.RGBA{0x10, 0x20, 0x30, 0xff}
+	img, err := RasterizeScene(scene, RasterOptions{Width: 400, Background: background})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 400 || bounds.Dy() != 200 {
+		t.Fatalf("image is %v, want 400x200", bounds)
+	}
+	tests := []struct {
+		name string
+		x, y int
+		c    color.RGBA
+	}{
+		{"keystone centre", 100, 100, color.RGBA{0, 0xff, 0, 0xff}},
+		{"inline styled centre", 300, 100, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{"line", 200, 20, color.RGBA{0, 0, 0xff, 0xff}},
+		{"background", 200, 100, background},
+		{"corner", 0, 199, background},
+	}
+	for _, test := range tests {
+		if c := img.RGBAAt(test.x, test.y); c != test.c {
+			t.Errorf("%s: pixel %d,%d = %v, want %v", test.name, test.x, test.y, c, test.c)
+		}
+	}
+
+	if _, err := RasterizeScene(scene, RasterOptions{Width: 0}); err == nil {
+		t.Error("RasterizeScene accepted a width of 0")
+	}
+}
+
+func TestPaintTranslucent(t *testing.T) {
+	r := NewRasterizer(0, 0, 1, 10, 10, color.RGBA{0, 0, 0xff, 0xff})
+	fill, _, err := ParseColor("rgba(255, 0, 0, 0.5)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Paint(Shape{Circle: true, Points: [][2]float64{{5, 5}}, Radius: 4, Fill: fill, HasFill: true})
+	// half of the red covers half of the blue background
+	if c := r.img.RGBAAt(5, 5); c != (color.RGBA{0x80, 0, 0x7f, 0xff}) {
+		t.Errorf("translucent fill painted %v", c)
+	}
+}
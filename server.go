@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cacheEntry[T any] struct {
+	modTime time.Time
+	value   T
+}
+
+type TreeServer struct {
+	InputDir string
+	// a built-in theme or a css file, empty for the default style
+	Theme string
+
+	mu sync.Mutex
+	// the cached values are shared by concurrent requests and must only be
+	// read, LoadTree moves the ascendancy trees before a tree is cached and
+	// the drawers keep their per request state in the TreeDrawer
+	trees        map[string]cacheEntry[Tree]
+	compactTrees map[string]cacheEntry[CompactTree]
+	descriptions map[string]cacheEntry[DocumentOptions]
+}
+
+func NewTreeServer(inputDir string, theme string) *TreeServer {
+	return &TreeServer{
+		InputDir:     inputDir,
+		Theme:        theme,
+		trees:        make(map[string]cacheEntry[Tree]),
+		compactTrees: make(map[string]cacheEntry[CompactTree]),
+		descriptions: make(map[string]cacheEntry[DocumentOptions]),
+	}
+}
+
+// returns the cached value for path, reloading it if the file changed on disk
+func loadCached[T any](mu *sync.Mutex, cache map[string]cacheEntry[T], path string, load func(string) (T, error)) (T, error) {
+	var zero T
+	info, err := os.Stat(path)
+	if err != nil {
+		return zero, err
+	}
+	mu.Lock()
+	entry, exists := cache[path]
+	mu.Unlock()
+	if exists && entry.modTime.Equal(info.ModTime()) {
+		return entry.value, nil
+	}
+	value, err := load(path)
+	if err != nil {
+		return zero, err
+	}
+	mu.Lock()
+	cache[path] = cacheEntry[T]{modTime: info.ModTime(), value: value}
+	mu.Unlock()
+	return value, nil
+}
+
+func (t *TreeServer) treeFile(r *http.Request, ext string) (TreeFile, error) {
+	kind, err := GetTreeKind(r.PathValue("kind"))
+	if err != nil {
+		return TreeFile{}, err
+	}
+	version := strings.TrimSuffix(r.PathValue("version"), ext)
+	if version == "" || strings.ContainsAny(version, `/\`) || strings.Contains(version, "..") {
+		return TreeFile{}, fmt.Errorf("invalid version %q", version)
+	}
+	return NewTreeFile(t.InputDir, kind, version), nil
+}
+
+func (t *TreeServer) style() (string, error) {
+	if t.Theme == "" {
+		return DefaultStyle, nil
+	}
+	if slices.Contains(ThemeNames(), t.Theme) {
+		return builtinTheme(t.Theme)
+	}
+	// read on every request so that themes can be edited without restarting
+	style, err := os.ReadFile(t.Theme)
+	if err != nil {
+		return "", err
+	}
+	return string(style), nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, fs.ErrNotExist) {
+		status = http.StatusNotFound
+	} else {
+		log.Printf("error: %v", err)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func (t *TreeServer) HandleSvg(w http.ResponseWriter, r *http.Request) {
+	file, err := t.treeFile(r, ".svg")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tree, err := loadCached(&t.mu, t.trees, file.Path, LoadTree)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	style, err := t.style()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	// only built-in themes, requests must not read arbitrary files
+	if query.Has("theme") {
+		theme := query.Get("theme")
+		if !slices.Contains(ThemeNames(), theme) {
+			http.Error(w, fmt.Sprintf("unknown theme %q, expected one of %s", theme, strings.Join(ThemeNames(), ", ")), http.StatusBadRequest)
+			return
+		}
+		style, err = builtinTheme(theme)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	options := DrawOptions{Style: style}
+	if query.Has("nodes") || query.Has("ascendancy") {
+		nodes, err := ParseNodeList(query.Get("nodes"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.Allocation = &Allocation{Nodes: nodes, Ascendancy: query.Get("ascendancy")}
+	}
+	if query.Has("metadata") {
+		fields, err := ParseMetadataFields(query.Get("metadata"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.Metadata = &MetadataOptions{Fields: fields}
+	}
+	if query.Has("describe") || query.Has("legend") {
+		// the export is only read and hashed again when it changes
+		description, err := loadCached(&t.mu, t.descriptions, file.Path, func(string) (DocumentOptions, error) {
+			return DescribeFile(file)
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		options.Document = &DocumentOptions{Describe: query.Has("describe"), Legend: query.Has("legend")}
+		options = options.WithDescription(description)
+	}
+	InitTreeDrawer(w, tree, options).Draw()
+}
+
+func (t *TreeServer) HandleJson(w http.ResponseWriter, r *http.Request) {
+	file, err := t.treeFile(r, ".json")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	compactTree, err := loadCached(&t.mu, t.compactTrees, file.Path, LoadCompactTree)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = WriteCompactJson(w, compactTree)
+	if err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+func (t *TreeServer) HandleVersions(w http.ResponseWriter, r *http.Request) {
+	versions := make(map[string][]string)
+	for _, kind := range TreeKinds {
+		files, err := FindTreeFiles(Config{InputDir: t.InputDir, Kinds: []TreeKind{kind}})
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			versions[kind.Name] = append(versions[kind.Name], file.Version)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+func (t *TreeServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /svg/{kind}/{version}", t.HandleSvg)
+	mux.HandleFunc("GET /json/{kind}/{version}", t.HandleJson)
+	mux.HandleFunc("GET /versions", t.HandleVersions)
+	return mux
+}
+
+func RunServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	in := flags.String("in", ".", "directory containing the atlastree/, skilltree/ and poe2tree/ exports")
+	addr := flags.String("addr", ":8080", "address to listen on")
+	theme := flags.String("theme", "", "style the SVGs with a built-in theme, "+strings.Join(ThemeNames(), ", ")+", or a CSS file re-read on every request (default dark), ?theme= selects a built-in theme per request")
+	flags.Parse(args)
+
+	// fails early on unknown themes and warns about unknown classes once
+	if *theme != "" {
+		_, err := LoadTheme(*theme)
+		if err != nil {
+			return err
+		}
+	}
+	server := NewTreeServer(*in, *theme)
+	log.Printf("Serving trees from %s on %s", *in, *addr)
+	return http.ListenAndServe(*addr, server.Handler())
+}
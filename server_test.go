@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Error(err)
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// the cached trees are shared by all requests, run with -race to check that
+// drawing never modifies them
+func TestServerConcurrentRequests(t *testing.T) {
+	server := httptest.NewServer(NewTreeServer("testdata", "").Handler())
+	defer server.Close()
+
+	queries := []string{
+		"",
+		"?nodes=101,102&ascendancy=Occultist",
+		"?nodes=101,102,103,104&ascendancy=Elementalist&theme=light",
+		"?metadata=all&legend&describe",
+		"?theme=print&nodes=110",
+	}
+	want := make([]string, len(queries))
+	for i, query := range queries {
+		status, body := get(t, server.URL+"/svg/passives/3.25.svg"+query)
+		if status != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", query, status, body)
+		}
+		want[i] = body
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4*len(queries); i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			query := queries[i%len(queries)]
+			_, body := get(t, server.URL+"/svg/passives/3.25.svg"+query)
+			if body != want[i%len(queries)] {
+				t.Errorf("GET %s returned a different svg while other requests were drawn", query)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			status, body := get(t, server.URL+"/json/passives/3.25.json")
+			if status != http.StatusOK || !strings.Contains(body, "Arcane Focus") {
+				t.Errorf("GET /json/passives/3.25.json: %d %s", status, body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// allocations of one request must not leak into the cached tree
+	_, body := get(t, server.URL+"/svg/passives/3.25.svg")
+	if body != want[0] {
+		t.Error("the cached tree was modified by earlier requests")
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	server := httptest.NewServer(NewTreeServer("testdata", "").Handler())
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/svg/passives/3.25.svg?theme=../cli_test.go", http.StatusBadRequest},
+		{"/svg/passives/3.25.svg?nodes=abc", http.StatusBadRequest},
+		{"/svg/unknown/3.25.svg", http.StatusBadRequest},
+		{"/svg/passives/..%2Fcli_test.svg", http.StatusBadRequest},
+		{"/svg/passives/9.99.svg", http.StatusNotFound},
+		{"/json/atlas/3.25.json", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		status, body := get(t, server.URL+test.path)
+		if status != test.status {
+			t.Errorf("GET %s = %d %s, want %d", test.path, status, body, test.status)
+		}
+	}
+
+	status, body := get(t, server.URL+"/versions")
+	if status != http.StatusOK || body != fmt.Sprintln(`{"passives":["3.25"]}`) {
+		t.Errorf("GET /versions = %d %s", status, body)
+	}
+}
+
+func TestServerTheme(t *testing.T) {
+	light, err := builtinTheme("light")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := t.TempDir() + "/custom.css"
+	err = os.WriteFile(file, []byte("circle { fill: #123456; }"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		theme string
+		query string
+		style string
+	}{
+		{"", "", DefaultStyle},
+		{"light", "", light},
+		{file, "", "circle { fill: #123456; }"},
+		// the query selects a built-in theme over the one of the server
+		{file, "?theme=light", light},
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(NewTreeServer("testdata", test.theme).Handler())
+		status, body := get(t, server.URL+"/svg/passives/3.25.svg"+test.query)
+		server.Close()
+		if status != http.StatusOK || !strings.Contains(body, test.style) {
+			t.Errorf("theme %q%s: %d, the svg does not embed the theme", test.theme, test.query, status)
+		}
+	}
+}
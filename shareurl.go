@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"treegen/internal/shareurl"
+)
+
+// a share url build checked against a tree
+type PassiveBuild struct {
+	shareurl.Build
+}
+
+func (b PassiveBuild) Class(tree Tree) (Classes, error) {
+	if b.ClassId < 0 || b.ClassId >= len(tree.Classes) {
+		return Classes{}, fmt.Errorf("unknown class id %d", b.ClassId)
+	}
+	return tree.Classes[b.ClassId], nil
+}
+
+// returns the name of the chosen ascendancy, or an empty string if none is chosen
+func (b PassiveBuild) AscendancyName(tree Tree) (string, error) {
+	if b.AscendancyId == 0 {
+		return "", nil
+	}
+	class, err := b.Class(tree)
+	if err != nil {
+		return "", err
+	}
+	if b.AscendancyId > len(class.Ascendancies) {
+		return "", fmt.Errorf("unknown ascendancy id %d for class %s", b.AscendancyId, class.Name)
+	}
+	return class.Ascendancies[b.AscendancyId-1].Name, nil
+}
+
+func (b PassiveBuild) AlternateAscendancyName(tree Tree) (string, error) {
+	if b.AlternateAscendancyId == 0 {
+		return "", nil
+	}
+	if b.AlternateAscendancyId > len(tree.AlternateAscendancies) {
+		return "", fmt.Errorf("unknown alternate ascendancy id %d", b.AlternateAscendancyId)
+	}
+	return tree.AlternateAscendancies[b.AlternateAscendancyId-1].Name, nil
+}
+
+// checks that the class, ascendancy, nodes and mastery effects exist in the tree
+func (b PassiveBuild) Validate(tree Tree) error {
+	_, err := b.Class(tree)
+	if err != nil {
+		return err
+	}
+	_, err = b.AscendancyName(tree)
+	if err != nil {
+		return err
+	}
+	_, err = b.AlternateAscendancyName(tree)
+	if err != nil {
+		return err
+	}
+	for _, hash := range b.Nodes {
+		if _, exists := tree.Nodes[fmt.Sprintf("%d", hash)]; !exists {
+			return fmt.Errorf("unknown node %d", hash)
+		}
+	}
+	for hash, effect := range b.MasteryEffects {
+		node, exists := tree.Nodes[fmt.Sprintf("%d", hash)]
+		if !exists || !node.IsMastery {
+			return fmt.Errorf("node %d is not a mastery", hash)
+		}
+		found := false
+		for _, masteryEffect := range node.MasteryEffects {
+			if masteryEffect.Effect == effect {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown effect %d for mastery %d", effect, hash)
+		}
+	}
+	return nil
+}
+
+func (b PassiveBuild) Allocation(tree Tree) (Allocation, error) {
+	ascendancy, err := b.AscendancyName(tree)
+	if err != nil {
+		return Allocation{}, err
+	}
+	return Allocation{Nodes: b.Nodes, Ascendancy: ascendancy}, nil
+}
+
+// NewPassiveBuild returns the build of a class and ascendancy, the class may
+// be left empty when an ascendancy is given
+func NewPassiveBuild(tree Tree, className string, ascendancy string, nodes []int) (PassiveBuild, error) {
+	build := PassiveBuild{shareurl.Build{Version: shareurl.MaxVersion, ClassId: -1, Nodes: nodes, MasteryEffects: make(map[int]int)}}
+	ascendancyClassId := -1
+	for classId, class := range tree.Classes {
+		if class.Name == className {
+			build.ClassId = classId
+		}
+		for ascendancyId, asc := range class.Ascendancies {
+			if asc.Name == ascendancy || asc.Id == ascendancy {
+				ascendancyClassId = classId
+				build.AscendancyId = ascendancyId + 1
+			}
+		}
+	}
+	if ascendancy != "" && build.AscendancyId == 0 {
+		return build, fmt.Errorf("unknown ascendancy %q", ascendancy)
+	}
+	if className == "" {
+		build.ClassId = ascendancyClassId
+	}
+	if build.ClassId < 0 {
+		return build, fmt.Errorf("unknown class %q", className)
+	}
+	if ascendancyClassId >= 0 && ascendancyClassId != build.ClassId {
+		return build, fmt.Errorf("ascendancy %s belongs to %s, not %s", ascendancy, tree.Classes[ascendancyClassId].Name, className)
+	}
+	return build, nil
+}
+
+type DecodedBuild struct {
+	Version             int         `json:"version"`
+	Class               string      `json:"class"`
+	Ascendancy          string      `json:"ascendancy,omitempty"`
+	AlternateAscendancy string      `json:"alternateAscendancy,omitempty"`
+	Nodes               []int       `json:"nodes"`
+	ClusterNodes        []int       `json:"clusterNodes,omitempty"`
+	MasteryEffects      map[int]int `json:"masteryEffects,omitempty"`
+}
+
+func RunUrl(args []string) error {
+	if len(args) == 0 || (args[0] != "decode" && args[0] != "encode") {
+		return fmt.Errorf("usage: treegen url decode [flags] <url> | treegen url encode [flags]")
+	}
+	fs, config := NewFlagSet("url " + args[0])
+	className := fs.String("class", "", "class name, only used when encoding")
+	ascendancy := fs.String("ascendancy", "", "ascendancy name, only used when encoding")
+	nodes := fs.String("nodes", "", "comma separated list of allocated node hashes, only used when encoding")
+	fs.Parse(args[1:])
+	if args[0] == "decode" && fs.NArg() != 1 {
+		return fmt.Errorf("expected exactly one url to decode")
+	} else if args[0] == "encode" {
+		err := CheckNoArgs(fs)
+		if err != nil {
+			return err
+		}
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	file, err := SelectTreeFile(cfg)
+	if err != nil {
+		return err
+	}
+	tree, err := LoadTree(file.Path)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "encode" {
+		allocated, err := ParseNodeList(*nodes)
+		if err != nil {
+			return err
+		}
+		build, err := NewPassiveBuild(tree, *className, *ascendancy, allocated)
+		if err != nil {
+			return err
+		}
+		err = build.Validate(tree)
+		if err != nil {
+			return err
+		}
+		encoded, err := shareurl.Encode(build.Build)
+		if err != nil {
+			return err
+		}
+		fmt.Println(encoded)
+		return nil
+	}
+
+	decoded, err := shareurl.Decode(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	build := PassiveBuild{decoded}
+	err = build.Validate(tree)
+	if err != nil {
+		return err
+	}
+	class, _ := build.Class(tree)
+	ascendancyName, _ := build.AscendancyName(tree)
+	alternateAscendancyName, _ := build.AlternateAscendancyName(tree)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(DecodedBuild{
+		Version:             build.Version,
+		Class:               class.Name,
+		Ascendancy:          ascendancyName,
+		AlternateAscendancy: alternateAscendancyName,
+		Nodes:               build.Nodes,
+		ClusterNodes:        build.ClusterNodes,
+		MasteryEffects:      build.MasteryEffects,
+	})
+}
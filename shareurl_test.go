@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"treegen/internal/shareurl"
+)
+
+func TestNewPassiveBuild(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		class, ascendancy string
+		classId           int
+		ascendancyId      int
+		err               string
+	}{
+		{"Witch", "", 1, 0, ""},
+		{"Witch", "Elementalist", 1, 2, ""},
+		{"", "Occultist", 1, 1, ""},
+		{"Scion", "Occultist", 0, 0, "belongs to Witch, not Scion"},
+		{"Ranger", "", 0, 0, "unknown class"},
+		{"Witch", "Raider", 0, 0, "unknown ascendancy"},
+	}
+	for _, test := range tests {
+		build, err := NewPassiveBuild(tree, test.class, test.ascendancy, []int{101})
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("NewPassiveBuild(%q, %q): got error %v, want %q", test.class, test.ascendancy, err, test.err)
+			}
+			continue
+		}
+		if err != nil || build.ClassId != test.classId || build.AscendancyId != test.ascendancyId {
+			t.Errorf("NewPassiveBuild(%q, %q) = %+v, %v", test.class, test.ascendancy, build, err)
+		}
+	}
+}
+
+func TestPassiveBuildValidate(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := PassiveBuild{shareurl.Build{ClassId: 1, AscendancyId: 1, Nodes: []int{101, 102}, MasteryEffects: map[int]int{110: 48385}}}
+	if err := valid.Validate(tree); err != nil {
+		t.Errorf("valid build: %v", err)
+	}
+	invalid := []PassiveBuild{
+		{shareurl.Build{ClassId: 5}},
+		{shareurl.Build{ClassId: 1, AscendancyId: 3 + 1}},
+		{shareurl.Build{ClassId: 1, Nodes: []int{999}}},
+		{shareurl.Build{ClassId: 1, MasteryEffects: map[int]int{101: 48385}}},
+		{shareurl.Build{ClassId: 1, MasteryEffects: map[int]int{110: 1}}},
+	}
+	for _, build := range invalid {
+		if err := build.Validate(tree); err == nil {
+			t.Errorf("%+v passed validation", build)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// maps the icon paths of the nodes to the sliced files of each sprite type,
+// relative to the manifest
+type IconManifest map[string]map[string]string
+
+type SpriteSlicer struct {
+	// directory containing local copies of the sprite sheets
+	AssetDir  string
+	OutputDir string
+	// index into the tree's image zoom levels, negative for the largest
+	ZoomLevel int
+	sheets    map[string]image.Image
+}
+
+func (s *SpriteSlicer) loadSheet(sprite Sprite) (image.Image, error) {
+	fileName := filepath.Join(s.AssetDir, spriteFileName(sprite.FileName))
+	if sheet, exists := s.sheets[fileName]; exists {
+		return sheet, nil
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	sheet, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", fileName, err)
+	}
+	if s.sheets == nil {
+		s.sheets = make(map[string]image.Image)
+	}
+	s.sheets[fileName] = sheet
+	return sheet, nil
+}
+
+// Slice writes every icon of every sprite type of the tree to its own png,
+// keyed by sprite type and icon path
+func (s *SpriteSlicer) Slice(tree Tree) (IconManifest, error) {
+	manifest := make(IconManifest)
+	spriteTypes := make([]string, 0, len(tree.Sprites))
+	for spriteType := range tree.Sprites {
+		spriteTypes = append(spriteTypes, spriteType)
+	}
+	sort.Strings(spriteTypes)
+
+	for _, spriteType := range spriteTypes {
+		sprite, exists := tree.SpriteSheet(spriteType, s.ZoomLevel)
+		if !exists || len(sprite.Coords) == 0 {
+			continue
+		}
+		sheet, err := s.loadSheet(sprite)
+		if err != nil {
+			return nil, err
+		}
+		icons := make([]string, 0, len(sprite.Coords))
+		for icon := range sprite.Coords {
+			icons = append(icons, icon)
+		}
+		sort.Strings(icons)
+		for _, icon := range icons {
+			coords := sprite.Coords[icon]
+			if !filepath.IsLocal(icon) {
+				return nil, fmt.Errorf("invalid icon path %q", icon)
+			}
+			rect := image.Rect(coords.X, coords.Y, coords.X+coords.W, coords.Y+coords.H)
+			if !rect.In(sheet.Bounds()) {
+				return nil, fmt.Errorf("icon %s is outside of sprite sheet %s", icon, spriteFileName(sprite.FileName))
+			}
+			fileName := path.Join(spriteType, icon)
+			if path.Ext(fileName) != ".png" {
+				fileName += ".png"
+			}
+			err = s.writeIcon(sheet, rect, fileName)
+			if err != nil {
+				return nil, err
+			}
+			if manifest[icon] == nil {
+				manifest[icon] = make(map[string]string)
+			}
+			manifest[icon][spriteType] = fileName
+		}
+	}
+	return manifest, nil
+}
+
+func (s *SpriteSlicer) writeIcon(sheet image.Image, rect image.Rectangle, fileName string) error {
+	subImager, ok := sheet.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return fmt.Errorf("sprite sheet does not support slicing")
+	}
+	outFileName := filepath.Join(s.OutputDir, filepath.FromSlash(fileName))
+	err := os.MkdirAll(filepath.Dir(outFileName), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	outFile, err := os.Create(outFileName)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	return png.Encode(outFile, subImager.SubImage(rect))
+}
+
+func RunSprites(args []string) error {
+	fs, config := NewFlagSet("sprites")
+	assets := fs.String("assets", "assets", "directory containing local copies of the sprite sheets")
+	zoom := fs.Int("zoom", -1, "index of the sprite zoom level to slice (default the largest)")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	file, err := SelectTreeFile(cfg)
+	if err != nil {
+		return err
+	}
+	tree, err := LoadTree(file.Path)
+	if err != nil {
+		return err
+	}
+
+	slicer := &SpriteSlicer{
+		AssetDir:  *assets,
+		OutputDir: filepath.Join(cfg.OutputDir, "icons", file.Kind.Name, file.Version),
+		ZoomLevel: *zoom,
+	}
+	fmt.Printf("Slicing sprites for %s %s\n", file.Kind.Name, file.Version)
+	manifest, err := slicer.Slice(tree)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(slicer.OutputDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	outFile, err := os.Create(filepath.Join(slicer.OutputDir, "manifest.json"))
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	err = json.NewEncoder(outFile).Encode(manifest)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d icons to %s\n", len(manifest), slicer.OutputDir)
+	return nil
+}
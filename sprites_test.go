@@ -0,0 +1,96 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSpriteSheet(t *testing.T, fileName string, w, h int) {
+	t.Helper()
+	sheet := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			sheet.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 0xff})
+		}
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, sheet); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpriteSlicer(t *testing.T) {
+	assets, out := t.TempDir(), t.TempDir()
+	writeSpriteSheet(t, filepath.Join(assets, "skills-3.png"), 64, 32)
+	tree := Tree{
+		ImageZoomLevels: []float64{0.1246, 0.3835},
+		Sprites: map[string]map[string]Sprite{
+			"normalActive": {"0.3835": {
+				FileName: "https://web.poecdn.com/image/passive-skill/skills-3.png?1a2b",
+				Coords: map[string]SpriteCoords{
+					"Art/2DArt/SkillIcons/passives/Intelligence.png": {X: 0, Y: 0, W: 16, H: 16},
+					"Art/2DArt/SkillIcons/passives/ArcaneFocus":      {X: 40, Y: 8, W: 24, H: 24},
+				},
+			}},
+			// sheets of other zoom levels are not sliced
+			"normalInactive": {"0.1246": {FileName: "missing.png", Coords: map[string]SpriteCoords{"x": {W: 1, H: 1}}}},
+		},
+	}
+	slicer := SpriteSlicer{AssetDir: assets, OutputDir: out, ZoomLevel: -1}
+	manifest, err := slicer.Slice(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := IconManifest{
+		"Art/2DArt/SkillIcons/passives/Intelligence.png": {"normalActive": "normalActive/Art/2DArt/SkillIcons/passives/Intelligence.png"},
+		"Art/2DArt/SkillIcons/passives/ArcaneFocus":      {"normalActive": "normalActive/Art/2DArt/SkillIcons/passives/ArcaneFocus.png"},
+	}
+	if !maps.EqualFunc(manifest, want, maps.Equal) {
+		t.Errorf("manifest = %v, want %v", manifest, want)
+	}
+	tests := []struct {
+		fileName string
+		size     image.Point
+		corner   color.RGBA
+	}{
+		{"normalActive/Art/2DArt/SkillIcons/passives/Intelligence.png", image.Pt(16, 16), color.RGBA{0, 0, 0, 0xff}},
+		{"normalActive/Art/2DArt/SkillIcons/passives/ArcaneFocus.png", image.Pt(24, 24), color.RGBA{40, 8, 0, 0xff}},
+	}
+	for _, test := range tests {
+		file, err := os.Open(filepath.Join(out, filepath.FromSlash(test.fileName)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		icon, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		// the icons start at the origin, whatever their place on the sheet
+		if bounds := icon.Bounds(); bounds != (image.Rectangle{Max: test.size}) {
+			t.Errorf("%s is %v, want %v", test.fileName, bounds, test.size)
+		}
+		if c := color.RGBAModel.Convert(icon.At(0, 0)); c != test.corner {
+			t.Errorf("%s starts with %v, want %v", test.fileName, c, test.corner)
+		}
+	}
+
+	outside := tree.Sprites["normalActive"]["0.3835"]
+	outside.Coords = map[string]SpriteCoords{"Art/Outside.png": {X: 48, Y: 16, W: 24, H: 24}}
+	tree.Sprites["normalActive"]["0.3835"] = outside
+	if _, err := slicer.Slice(tree); err == nil || !strings.Contains(err.Error(), "outside of sprite sheet skills-3.png") {
+		t.Errorf("got error %v, want the icon outside of the sheet", err)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// a rule of the embedded css, only simple selectors made of a tag and
+// classes are supported, which covers everything the drawers emit
+type StyleRule struct {
+	Tag          string
+	Classes      []string
+	Declarations map[string]string
+	order        int
+}
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+func ParseStyle(css string) []StyleRule {
+	rules := make([]StyleRule, 0)
+	css = cssComment.ReplaceAllString(css, "")
+	for _, block := range strings.Split(css, "}") {
+		selectors, body, found := strings.Cut(block, "{")
+		if !found {
+			continue
+		}
+		declarations := ParseDeclarations(body)
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+			// pseudo classes, attribute selectors and combinators are skipped
+			if selector == "" || strings.ContainsAny(selector, ":[ >+~") {
+				continue
+			}
+			parts := strings.Split(selector, ".")
+			rules = append(rules, StyleRule{
+				Tag:          parts[0],
+				Classes:      parts[1:],
+				Declarations: declarations,
+				order:        len(rules),
+			})
+		}
+	}
+	// later and more specific rules take precedence
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity() < rules[j].specificity()
+	})
+	return rules
+}
+
+// the properties holding a color, declarations with colors ParseColor does
+// not support are dropped like browsers drop invalid declarations, so that
+// the rules before them apply
+var colorProperties = []string{"fill", "stroke", "background-color"}
+
+// the dropped declarations already warned about, styles are parsed for every
+// render
+var droppedDeclarations sync.Map
+
+func ParseDeclarations(s string) map[string]string {
+	declarations := make(map[string]string)
+	for _, declaration := range strings.Split(s, ";") {
+		property, value, found := strings.Cut(declaration, ":")
+		if !found {
+			continue
+		}
+		property = strings.TrimSpace(property)
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		if slices.Contains(colorProperties, property) {
+			if _, _, err := ParseColor(value); err != nil {
+				if _, warned := droppedDeclarations.LoadOrStore(property+":"+value, true); !warned {
+					log.Printf("warning: ignoring %s: %s, %v", property, value, err)
+				}
+				continue
+			}
+		}
+		declarations[property] = value
+	}
+	return declarations
+}
+
+func (r StyleRule) specificity() int {
+	specificity := len(r.Classes) * 10
+	if r.Tag != "" {
+		specificity++
+	}
+	return specificity
+}
+
+func (r StyleRule) Matches(tag string, classes []string) bool {
+	if r.Tag != "" && r.Tag != tag {
+		return false
+	}
+	for _, class := range r.Classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// ComputedStyle returns the declarations applying to an element, the inline
+// style overrides the rules
+func ComputedStyle(rules []StyleRule, tag string, classes []string, inline string) map[string]string {
+	style := make(map[string]string)
+	for _, rule := range rules {
+		if rule.Matches(tag, classes) {
+			for property, value := range rule.Declarations {
+				style[property] = value
+			}
+		}
+	}
+	for property, value := range ParseDeclarations(inline) {
+		style[property] = value
+	}
+	return style
+}
+
+// the css color keywords most likely to appear in themes
+var namedColors = map[string]string{
+	"black": "#000000", "white": "#ffffff", "gray": "#808080", "grey": "#808080",
+	"silver": "#c0c0c0", "red": "#ff0000", "maroon": "#800000", "orange": "#ffa500",
+	"yellow": "#ffff00", "gold": "#ffd700", "green": "#008000", "lime": "#00ff00",
+	"teal": "#008080", "cyan": "#00ffff", "aqua": "#00ffff", "blue": "#0000ff",
+	"navy": "#000080", "purple": "#800080", "magenta": "#ff00ff", "fuchsia": "#ff00ff",
+	"pink": "#ffc0cb", "brown": "#a52a2a",
+}
+
+// ParseColor parses hex, rgb(), rgba(), hsl(), hsla() and the common named
+// colors, returning false for none and anything else that can not be painted.
+// Translucent colors are premultiplied with their alpha like color.RGBA.
+func ParseColor(s string) (color.RGBA, bool, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "none" || s == "transparent" || strings.HasPrefix(s, "url(") {
+		return color.RGBA{}, false, nil
+	}
+	if named, exists := namedColors[s]; exists {
+		s = named
+	}
+	if name, args, found := strings.Cut(s, "("); found && strings.HasSuffix(args, ")") {
+		return parseColorFunction(name, strings.TrimSuffix(args, ")"))
+	}
+	hex, found := strings.CutPrefix(s, "#")
+	if !found {
+		return color.RGBA{}, false, fmt.Errorf("unsupported color %q", s)
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.RGBA{}, false, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}, true, nil
+}
+
+// parses the arguments of rgb() and hsl() and their alpha variants, separated
+// by commas or, with the alpha after a slash, by spaces
+func parseColorFunction(name string, args string) (color.RGBA, bool, error) {
+	invalid := fmt.Errorf("invalid color %s(%s)", name, args)
+	values := strings.Fields(strings.NewReplacer(",", " ", "/", " ").Replace(args))
+	if len(values) != 3 && len(values) != 4 {
+		return color.RGBA{}, false, invalid
+	}
+	alpha := 1.0
+	if len(values) == 4 {
+		var err error
+		alpha, err = parseColorValue(values[3], 1)
+		if err != nil || alpha < 0 || alpha > 1 {
+			return color.RGBA{}, false, invalid
+		}
+	}
+	var r, g, b float64
+	switch name {
+	case "rgb", "rgba":
+		channels := [3]float64{}
+		for i := range channels {
+			channel, err := parseColorValue(values[i], 255)
+			if err != nil || channel < 0 || channel > 255 {
+				return color.RGBA{}, false, invalid
+			}
+			channels[i] = channel / 255
+		}
+		r, g, b = channels[0], channels[1], channels[2]
+	case "hsl", "hsla":
+		hue, err := strconv.ParseFloat(strings.TrimSuffix(values[0], "deg"), 64)
+		if err != nil {
+			return color.RGBA{}, false, invalid
+		}
+		saturation, err1 := parseColorValue(values[1], 1)
+		lightness, err2 := parseColorValue(values[2], 1)
+		if err1 != nil || err2 != nil || !strings.HasSuffix(values[1], "%") || !strings.HasSuffix(values[2], "%") ||
+			saturation < 0 || saturation > 1 || lightness < 0 || lightness > 1 {
+			return color.RGBA{}, false, invalid
+		}
+		r, g, b = hslToRgb(hue, saturation, lightness)
+	default:
+		return color.RGBA{}, false, fmt.Errorf("unsupported color %s(%s)", name, args)
+	}
+	if alpha == 0 {
+		return color.RGBA{}, false, nil
+	}
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(v * alpha * 255))
+	}
+	return color.RGBA{R: channel(r), G: channel(g), B: channel(b), A: uint8(math.Round(alpha * 255))}, true, nil
+}
+
+// parses a number or a percentage of full
+func parseColorValue(s string, full float64) (float64, error) {
+	if percentage, found := strings.CutSuffix(s, "%"); found {
+		value, err := strconv.ParseFloat(percentage, 64)
+		return value / 100 * full, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// converts a hue in degrees and saturation and lightness between 0 and 1 to
+// red, green and blue between 0 and 1, as in the css color specification
+func hslToRgb(hue, saturation, lightness float64) (float64, float64, float64) {
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+	f := func(n float64) float64 {
+		k := math.Mod(n+hue/30, 12)
+		a := saturation * min(lightness, 1-lightness)
+		return lightness - a*max(-1, min(k-3, 9-k, 1))
+	}
+	return f(0), f(8), f(4)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"image/color"
+	"maps"
+	"testing"
+)
+
+func TestComputedStyle(t *testing.T) {
+	rules := ParseStyle(`
+/* later and more specific rules win */
+circle.keystone { fill: #00ff00; }
+circle { fill: #ff0000; stroke: #000000 !important; }
+.allocated { stroke: #ffffff; }
+circle:hover, g > circle { fill: #0000ff; }
+`)
+	tests := []struct {
+		classes []string
+		inline  string
+		style   map[string]string
+	}{
+		{nil, "", map[string]string{"fill": "#ff0000", "stroke": "#000000"}},
+		{[]string{"keystone"}, "", map[string]string{"fill": "#00ff00", "stroke": "#000000"}},
+		{[]string{"keystone", "allocated"}, "", map[string]string{"fill": "#00ff00", "stroke": "#ffffff"}},
+		{[]string{"keystone"}, "fill: url(#icon)", map[string]string{"fill": "url(#icon)", "stroke": "#000000"}},
+	}
+	for _, test := range tests {
+		style := ComputedStyle(rules, "circle", test.classes, test.inline)
+		if !maps.Equal(style, test.style) {
+			t.Errorf("ComputedStyle(circle %v, %q) = %v, want %v", test.classes, test.inline, style, test.style)
+		}
+	}
+	if style := ComputedStyle(rules, "line", nil, ""); len(style) != 0 {
+		t.Errorf("ComputedStyle(line) = %v, want no declarations", style)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		s       string
+		c       color.RGBA
+		visible bool
+	}{
+		{"#1a2B3c", color.RGBA{0x1a, 0x2b, 0x3c, 0xff}, true},
+		{"#fa0", color.RGBA{0xff, 0xaa, 0x00, 0xff}, true},
+		{"rgb(1, 2, 255)", color.RGBA{1, 2, 255, 0xff}, true},
+		{" Gold ", color.RGBA{0xff, 0xd7, 0x00, 0xff}, true},
+		{"rgb(100%, 0%, 50%)", color.RGBA{0xff, 0, 0x80, 0xff}, true},
+		{"rgb(255 128 0)", color.RGBA{0xff, 0x80, 0, 0xff}, true},
+		// translucent colors are premultiplied
+		{"rgba(255, 0, 0, 0.5)", color.RGBA{0x80, 0, 0, 0x80}, true},
+		{"rgb(0 0 255 / 25%)", color.RGBA{0, 0, 0x40, 0x40}, true},
+		{"rgba(255, 0, 0, 0)", color.RGBA{}, false},
+		{"hsl(0, 100%, 50%)", color.RGBA{0xff, 0, 0, 0xff}, true},
+		{"hsl(120deg 100% 25%)", color.RGBA{0, 0x80, 0, 0xff}, true},
+		{"hsl(-120, 100%, 50%)", color.RGBA{0, 0, 0xff, 0xff}, true},
+		{"hsl(0, 0%, 100%)", color.RGBA{0xff, 0xff, 0xff, 0xff}, true},
+		{"hsla(240, 100%, 50%, 0.5)", color.RGBA{0, 0, 0x80, 0x80}, true},
+		{"none", color.RGBA{}, false},
+		{"transparent", color.RGBA{}, false},
+		{"url(#icon)", color.RGBA{}, false},
+		{"", color.RGBA{}, false},
+	}
+	for _, test := range tests {
+		c, visible, err := ParseColor(test.s)
+		if err != nil || c != test.c || visible != test.visible {
+			t.Errorf("ParseColor(%q) = %v, %v, %v, want %v, %v", test.s, c, visible, err, test.c, test.visible)
+		}
+	}
+	for _, s := range []string{"#12345", "#ggg", "rgb(1, 2)", "rgb(1, 2, 256)", "rgba(1, 2, 3, 2)", "hsl(0, 50, 50%)", "hsl(0, 120%, 50%)", "currentcolor", "lab(50% 40 59)"} {
+		if _, _, err := ParseColor(s); err == nil {
+			t.Errorf("ParseColor(%q) should fail", s)
+		}
+	}
+}
+
+func TestParseStyleDropsUnsupportedColors(t *testing.T) {
+	rules := ParseStyle(`
+circle { fill: #ff0000; stroke: #000000; }
+circle.keystone { fill: currentColor; stroke: var(--stroke); stroke-width: 3; }
+`)
+	// the rules before the dropped declarations apply, as in browsers
+	style := ComputedStyle(rules, "circle", []string{"keystone"}, "fill: lab(50% 40 59)")
+	want := map[string]string{"fill": "#ff0000", "stroke": "#000000", "stroke-width": "3"}
+	if !maps.Equal(style, want) {
+		t.Errorf("ComputedStyle = %v, want %v", style, want)
+	}
+
+	shape, err := NewShape(rules, "circle", map[string]string{"class": "keystone"})
+	if err != nil || shape.Fill != (color.RGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("NewShape = %+v, %v", shape, err)
+	}
+	// invalid presentation attributes are dropped the same way
+	shape, err = NewShape(nil, "circle", map[string]string{"fill": "currentColor"})
+	if err != nil || shape.HasFill {
+		t.Errorf("NewShape with an invalid fill attribute = %+v, %v", shape, err)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+//go:embed themes/*.css
+var themeFiles embed.FS
+
+// the built-in themes override the rules of the base theme, which styles every
+// class of ThemeClasses
+//
+//go:embed themes/base.css
+var baseTheme string
+
+//go:embed themes/dark.css
+var darkTheme string
+
+// the style of the html viewers, rasterized outputs and the server when no
+// theme is selected
+var DefaultStyle = composeTheme(darkTheme)
+
+// ThemeClasses are the classes DrawNode, DrawConnection and the other drawers
+// emit. They are the contract between the drawers and the themes, renaming or
+// removing one breaks custom themes.
+var ThemeClasses = []string{
+	// nodes and connections
+	"keystone", "mastery", "attribute", "weapon-set", "isolated", "ascendancy",
+	"allocated", "added", "removed", "changed", "moved",
+	// groups and classes
+	"orbit", "class-start", "ascendancy-frame", "class-name", "ascendancy-name", "flavour-text",
+	// labels and legend
+	"label", "notable", "legend-background", "legend-title", "legend-label",
+	// html viewer
+	"search-match",
+}
+
+// a rule of a theme with the comments before it, kept as written so that
+// composed themes read like the theme files
+type themeRule struct {
+	Comments     []string
+	Selector     string
+	Declarations [][2]string
+}
+
+var themeRulePattern = regexp.MustCompile(`(?s)\s*((?:/\*.*?\*/\s*)*)([^{}/]*?)\s*\{([^}]*)\}`)
+
+// splits a theme into its leading comment and its rules
+func parseThemeRules(css string) (string, []themeRule) {
+	description := ThemeDescription(css)
+	if description != "" {
+		_, css, _ = strings.Cut(css, "*/")
+	}
+	rules := make([]themeRule, 0)
+	for _, match := range themeRulePattern.FindAllStringSubmatch(css, -1) {
+		rule := themeRule{Selector: strings.TrimSpace(match[2])}
+		rule.Comments = cssComment.FindAllString(match[1], -1)
+		for _, declaration := range strings.Split(match[3], ";") {
+			property, value, found := strings.Cut(declaration, ":")
+			if found {
+				rule.Declarations = append(rule.Declarations, [2]string{strings.TrimSpace(property), strings.TrimSpace(value)})
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return description, rules
+}
+
+func (r themeRule) String() string {
+	var b strings.Builder
+	for _, comment := range r.Comments {
+		b.WriteString(comment + "\n")
+	}
+	b.WriteString(r.Selector + " {\n")
+	for _, declaration := range r.Declarations {
+		fmt.Fprintf(&b, "\t%s: %s;\n", declaration[0], declaration[1])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// composeTheme merges the overrides of a built-in theme into the rules of the
+// base theme. The overridden declarations are replaced in place rather than
+// appended, so the rules keep the order of the base theme, which decides
+// between rules of the same specificity. Rules the base does not have are
+// added at the end.
+func composeTheme(override string) string {
+	description, overrides := parseThemeRules(override)
+	_, rules := parseThemeRules(baseTheme)
+	for _, o := range overrides {
+		i := slices.IndexFunc(rules, func(r themeRule) bool { return r.Selector == o.Selector })
+		if i < 0 {
+			rules = append(rules, o)
+			continue
+		}
+		for _, declaration := range o.Declarations {
+			j := slices.IndexFunc(rules[i].Declarations, func(d [2]string) bool { return d[0] == declaration[0] })
+			if j < 0 {
+				rules[i].Declarations = append(rules[i].Declarations, declaration)
+			} else {
+				rules[i].Declarations[j] = declaration
+			}
+		}
+	}
+	blocks := make([]string, len(rules))
+	for i, rule := range rules {
+		blocks[i] = rule.String()
+	}
+	return "/* " + description + " */\n\n" + strings.Join(blocks, "\n")
+}
+
+func builtinTheme(name string) (string, error) {
+	if name == "base" {
+		return "", fmt.Errorf("unknown theme %q", name)
+	}
+	data, err := themeFiles.ReadFile("themes/" + name + ".css")
+	if err != nil {
+		return "", fmt.Errorf("unknown theme %q", name)
+	}
+	return composeTheme(string(data)), nil
+}
+
+func ThemeNames() []string {
+	entries, err := themeFiles.ReadDir("themes")
+	if err != nil {
+		panic(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".css")
+		if name != "base" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// returns the leading comment of a theme
+func ThemeDescription(css string) string {
+	css = strings.TrimSpace(css)
+	if !strings.HasPrefix(css, "/*") {
+		return ""
+	}
+	description, _, _ := strings.Cut(strings.TrimPrefix(css, "/*"), "*/")
+	return strings.TrimSpace(description)
+}
+
+// LoadTheme returns the css of a built-in theme or of a css file
+func LoadTheme(theme string) (string, error) {
+	if slices.Contains(ThemeNames(), theme) {
+		return builtinTheme(theme)
+	}
+	data, err := os.ReadFile(theme)
+	if err != nil {
+		if os.IsNotExist(err) && !strings.HasSuffix(theme, ".css") {
+			return "", fmt.Errorf("unknown theme %q, expected one of %s or a CSS file", theme, strings.Join(ThemeNames(), ", "))
+		}
+		return "", err
+	}
+	css := string(data)
+	for _, class := range UnknownThemeClasses(css) {
+		log.Printf("warning: theme %s styles class %q, which is never drawn", theme, class)
+	}
+	return css, nil
+}
+
+// returns the classes a theme styles that are not part of ThemeClasses,
+// usually typos
+func UnknownThemeClasses(css string) []string {
+	unknown := make([]string, 0)
+	for _, rule := range ParseStyle(css) {
+		for _, class := range rule.Classes {
+			if !slices.Contains(ThemeClasses, class) && !slices.Contains(unknown, class) {
+				unknown = append(unknown, class)
+			}
+		}
+	}
+	return unknown
+}
+
+// ThemeBackground returns the background color a theme gives the svg, images
+// are painted onto it
+func ThemeBackground(css string) color.RGBA {
+	style := ComputedStyle(ParseStyle(css), "svg", nil, "")
+	background, exists := style["background-color"]
+	if !exists {
+		background = style["background"]
+	}
+	c, ok, err := ParseColor(background)
+	if err != nil || !ok {
+		return color.RGBA{A: 0xff}
+	}
+	return c
+}
+
+// returns the options for outputs painted from the parsed svg, which need the
+// css itself and can not show the title or legend text
+func (o DrawOptions) ForRaster() DrawOptions {
+	if o.Style == "" {
+		o.Style = DefaultStyle
+	}
+	o.StyleLink = ""
+	o.Document = nil
+	return o
+}
+
+// returns the background color of a rasterized output, from the flag or the
+// theme
+func RasterBackground(flagValue string, options DrawOptions) (color.RGBA, error) {
+	if flagValue == "" {
+		return ThemeBackground(options.Style), nil
+	}
+	c, ok, err := ParseColor(flagValue)
+	if err != nil || !ok {
+		return c, fmt.Errorf("invalid background color %q", flagValue)
+	}
+	return c, nil
+}
+
+func RunThemes(args []string) error {
+	fs := flag.NewFlagSet("themes", flag.ExitOnError)
+	out := fs.String("out", ".", "directory to write the themes/ to")
+	list := fs.Bool("list", false, "only list the built-in themes")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(*out, "themes")
+	if !*list {
+		err = os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range ThemeNames() {
+		css, err := builtinTheme(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-14s %s\n", name, ThemeDescription(css))
+		if *list {
+			continue
+		}
+		err = os.WriteFile(filepath.Join(dir, name+".css"), []byte(css), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestThemeNames(t *testing.T) {
+	names := ThemeNames()
+	want := []string{"colorblind", "dark", "high-contrast", "light", "print"}
+	if !slices.Equal(names, want) {
+		t.Errorf("ThemeNames = %v, want %v", names, want)
+	}
+	if _, err := builtinTheme("base"); err == nil {
+		t.Error("the base theme is not a theme of its own")
+	}
+	if _, err := builtinTheme("sepia"); err == nil {
+		t.Error("builtinTheme accepted an unknown theme")
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	dark, err := builtinTheme("dark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if DefaultStyle != dark {
+		t.Error("DefaultStyle is not the dark theme")
+	}
+	baseRules := ParseStyle(baseTheme)
+	for _, name := range ThemeNames() {
+		css, err := builtinTheme(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ThemeDescription(css) == "" {
+			t.Errorf("%s: no description", name)
+		}
+		if unknown := UnknownThemeClasses(css); len(unknown) != 0 {
+			t.Errorf("%s: styles unknown classes %v", name, unknown)
+		}
+		// the themes change how the rules of the base look, they can not drop
+		// any of them
+		rules := ParseStyle(css)
+		for _, base := range baseRules {
+			i := slices.IndexFunc(rules, func(r StyleRule) bool {
+				return r.Tag == base.Tag && slices.Equal(r.Classes, base.Classes)
+			})
+			if i < 0 {
+				t.Errorf("%s: rule %s.%s of the base is missing", name, base.Tag, strings.Join(base.Classes, "."))
+				continue
+			}
+			for property := range base.Declarations {
+				if _, exists := rules[i].Declarations[property]; !exists {
+					t.Errorf("%s: %s of %s.%s is missing", name, property, base.Tag, strings.Join(base.Classes, "."))
+				}
+			}
+		}
+	}
+}
+
+func TestComposeTheme(t *testing.T) {
+	override := `/* Sepia: a test theme */
+
+/* Node styles */
+circle {
+	fill: #704214;
+	opacity: 0.9;
+}
+
+text.search-match {
+	fill: #ffffff;
+}
+`
+	css := composeTheme(override)
+	if ThemeDescription(css) != "Sepia: a test theme" {
+		t.Errorf("description = %q", ThemeDescription(css))
+	}
+	if strings.Contains(css, "Base:") {
+		t.Error("the composed theme kept the description of the base")
+	}
+	// overridden declarations replace those of the base in place and rules the
+	// base does not have come last
+	circle := "/* Node styles */\ncircle {\n\tfill: #704214;\n\tstroke: #8b8b8b;\n\tstroke-width: 2;\n\topacity: 0.9;\n}\n"
+	if !strings.Contains(css, circle) {
+		t.Errorf("composed theme does not contain\n%s", circle)
+	}
+	if !strings.HasSuffix(css, "text.search-match {\n\tfill: #ffffff;\n}\n") {
+		t.Error("the new rule is not at the end")
+	}
+	if strings.Index(css, "circle {") > strings.Index(css, "circle.keystone {") {
+		t.Error("the rules are not in the order of the base")
+	}
+}
+
+func TestThemeBackground(t *testing.T) {
+	light, err := builtinTheme("light")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := ThemeBackground(light); c.R != 0xf7 || c.G != 0xf4 || c.B != 0xee {
+		t.Errorf("light background = %v", c)
+	}
+	if c := ThemeBackground("svg { background: hsl(0, 100%, 50%); }"); c.R != 0xff || c.G != 0 || c.B != 0 {
+		t.Errorf("hsl background = %v", c)
+	}
+	// themes without a background are painted onto black
+	if c := ThemeBackground("circle { fill: #ffffff; }"); c.R != 0 || c.A != 0xff {
+		t.Errorf("default background = %v", c)
+	}
+}
+
+func TestThemeUrl(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		// the import is written in a cdata section, so the query is not html escaped
+		{"http://x/a.css?a=1&b=2", `@import url("http://x/a.css?a=1&b=2");`},
+		{`http://x/"a\b".css`, `@import url("http://x/\"a\\b\".css");`},
+	}
+	for _, test := range tests {
+		svg := renderSvg(t, tree, DrawOptions{StyleLink: test.url})
+		if !strings.Contains(svg, test.want) {
+			t.Errorf("%s imported as %s", test.url, svg[:strings.Index(svg, "</defs>")])
+		}
+	}
+
+	fs, _ := NewFlagSet("svg")
+	drawOptions := AddDrawFlags(fs)
+	if err := fs.Parse([]string{"-theme-url", "http://x/a.css?]]><script>"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := drawOptions(); err == nil {
+		t.Error("accepted a theme url ending the cdata section")
+	}
+}
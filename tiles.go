@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// the scale of the most detailed zoom level for trees without image zoom
+// levels, matching the largest one of the official exports
+const defaultTileScale = 0.3835
+
+type TileOptions struct {
+	TileSize   int
+	Background color.RGBA
+}
+
+type TileZoomLevel struct {
+	Zoom    int     `json:"zoom"`
+	Scale   float64 `json:"scale"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Columns int     `json:"columns"`
+	Rows    int     `json:"rows"`
+}
+
+// TileMetadata describes a tile pyramid, tile x, y at a zoom level covers the
+// tree coordinates from minX + x * tileSize / scale and minY + y * tileSize /
+// scale onwards
+type TileMetadata struct {
+	Format     string          `json:"format"`
+	TileSize   int             `json:"tileSize"`
+	MinX       float64         `json:"minX"`
+	MinY       float64         `json:"minY"`
+	MaxX       float64         `json:"maxX"`
+	MaxY       float64         `json:"maxY"`
+	MinZoom    int             `json:"minZoom"`
+	MaxZoom    int             `json:"maxZoom"`
+	ZoomLevels []TileZoomLevel `json:"zoomLevels"`
+}
+
+// TileZoomLevels returns the zoom levels of a pyramid, each level doubling the
+// scale of the previous one as slippy maps expect. The most detailed level
+// uses the largest image zoom of the tree and the least detailed one fits the
+// whole tree into a single tile.
+func TileZoomLevels(viewBox [4]float64, imageZoomLevels []float64, tileSize int) []TileZoomLevel {
+	maxScale := defaultTileScale
+	if len(imageZoomLevels) > 0 {
+		maxScale = imageZoomLevels[len(imageZoomLevels)-1]
+	}
+	extent := max(viewBox[2], viewBox[3]) * maxScale
+	maxZoom := max(0, int(math.Ceil(math.Log2(extent/float64(tileSize)))))
+
+	levels := make([]TileZoomLevel, 0, maxZoom+1)
+	for zoom := 0; zoom <= maxZoom; zoom++ {
+		scale := maxScale / math.Pow(2, float64(maxZoom-zoom))
+		width := int(math.Ceil(viewBox[2] * scale))
+		height := int(math.Ceil(viewBox[3] * scale))
+		levels = append(levels, TileZoomLevel{
+			Zoom:    zoom,
+			Scale:   scale,
+			Width:   width,
+			Height:  height,
+			Columns: (width + tileSize - 1) / tileSize,
+			Rows:    (height + tileSize - 1) / tileSize,
+		})
+	}
+	return levels
+}
+
+// inside reports whether a shape may paint pixels of the given area in tree
+// coordinates
+func (s Shape) inside(minX, minY, maxX, maxY float64) bool {
+	pad := s.Radius + s.StrokeWidth
+	for i, p := range s.Points {
+		if p[0]+pad >= minX && p[0]-pad <= maxX && p[1]+pad >= minY && p[1]-pad <= maxY {
+			return true
+		}
+		// a segment may cross the area without any of its ends inside
+		if i > 0 {
+			q := s.Points[i-1]
+			if max(p[0], q[0])+pad >= minX && min(p[0], q[0])-pad <= maxX && max(p[1], q[1])+pad >= minY && min(p[1], q[1])-pad <= maxY {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteTiles renders a tree into <dir>/<zoom>/<x>/<y>.png tiles and writes
+// their metadata.json next to them
+func WriteTiles(dir string, tree Tree, drawOptions DrawOptions, options TileOptions) (TileMetadata, error) {
+	drawOptions = drawOptions.ForRaster()
+	var svgBuffer bytes.Buffer
+	WriteSvg(&svgBuffer, tree, drawOptions)
+	scene, err := ParseScene(svgBuffer.Bytes())
+	if err != nil {
+		return TileMetadata{}, err
+	}
+	viewBox := scene.ViewBox
+	metadata := TileMetadata{
+		Format:     "png",
+		TileSize:   options.TileSize,
+		MinX:       viewBox[0],
+		MinY:       viewBox[1],
+		MaxX:       viewBox[0] + viewBox[2],
+		MaxY:       viewBox[1] + viewBox[3],
+		ZoomLevels: TileZoomLevels(viewBox, tree.ImageZoomLevels, options.TileSize),
+	}
+	metadata.MaxZoom = len(metadata.ZoomLevels) - 1
+
+	for _, level := range metadata.ZoomLevels {
+		size := float64(options.TileSize) / level.Scale
+		for x := 0; x < level.Columns; x++ {
+			columnDir := filepath.Join(dir, strconv.Itoa(level.Zoom), strconv.Itoa(x))
+			err = os.MkdirAll(columnDir, os.ModePerm)
+			if err != nil {
+				return metadata, err
+			}
+			for y := 0; y < level.Rows; y++ {
+				minX, minY := viewBox[0]+float64(x)*size, viewBox[1]+float64(y)*size
+				r := NewRasterizer(minX, minY, level.Scale, options.TileSize, options.TileSize, options.Background)
+				// strokes are at least a pixel wide, whatever the scale
+				margin := 2 / level.Scale
+				for _, shape := range scene.Shapes {
+					if shape.inside(minX-margin, minY-margin, minX+size+margin, minY+size+margin) {
+						r.Paint(shape)
+					}
+				}
+				err = savePng(filepath.Join(columnDir, strconv.Itoa(y)+".png"), r)
+				if err != nil {
+					return metadata, err
+				}
+			}
+		}
+	}
+
+	outFile, err := os.Create(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return metadata, err
+	}
+	defer outFile.Close()
+	return metadata, json.NewEncoder(outFile).Encode(metadata)
+}
+
+func savePng(fileName string, r *Rasterizer) error {
+	outFile, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	return png.Encode(outFile, r.img)
+}
+
+func (c Config) TilesDir(file TreeFile) string {
+	return filepath.Join(c.OutputDir, "tiles", file.Kind.Name, file.Version)
+}
+
+func RunTiles(args []string) error {
+	fs, config := NewFlagSet("tiles")
+	drawOptions := AddDrawFlags(fs)
+	tileSize := fs.Int("tile-size", 256, "width and height of the tiles in pixels")
+	background := fs.String("background", "", "background color of the tiles (default the background of the theme)")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	options, err := drawOptions()
+	if err != nil {
+		return err
+	}
+	if *tileSize <= 0 {
+		return fmt.Errorf("invalid tile size %d, must be greater than 0", *tileSize)
+	}
+	backgroundColor, err := RasterBackground(*background, options.ForRaster())
+	if err != nil {
+		return err
+	}
+	tileOptions := TileOptions{TileSize: *tileSize, Background: backgroundColor}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Generating tiles for %s %s\n", file.Kind.Name, file.Version)
+		tree, err := LoadTree(file.Path)
+		if err != nil {
+			return err
+		}
+		metadata, err := WriteTiles(cfg.TilesDir(file), tree, options, tileOptions)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote zoom levels %d to %d to %s\n", metadata.MinZoom, metadata.MaxZoom, cfg.TilesDir(file))
+	}
+	return nil
+}
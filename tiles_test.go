@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestTileZoomLevels(t *testing.T) {
+	tests := []struct {
+		name            string
+		viewBox         [4]float64
+		imageZoomLevels []float64
+		tileSize        int
+		// columns and rows of each level
+		grid [][2]int
+	}{
+		// 3400 * 0.3835 = 1304 pixels need three doublings of a 256 pixel tile
+		{"3.25", [4]float64{-1200, -362, 3400, 724}, []float64{0.1246, 0.3835}, 256, [][2]int{{1, 1}, {2, 1}, {3, 1}, {6, 2}}},
+		{"taller than wide", [4]float64{0, 0, 724, 3400}, []float64{0.3835}, 256, [][2]int{{1, 1}, {1, 2}, {1, 3}, {2, 6}}},
+		{"default scale", [4]float64{0, 0, 3400, 724}, nil, 512, [][2]int{{1, 1}, {2, 1}, {3, 1}}},
+		{"smaller than a tile", [4]float64{0, 0, 100, 100}, []float64{0.5}, 256, [][2]int{{1, 1}}},
+	}
+	for _, test := range tests {
+		levels := TileZoomLevels(test.viewBox, test.imageZoomLevels, test.tileSize)
+		if len(levels) != len(test.grid) {
+			t.Errorf("%s: got %d levels, want %d", test.name, len(levels), len(test.grid))
+			continue
+		}
+		maxScale := defaultTileScale
+		if len(test.imageZoomLevels) > 0 {
+			maxScale = test.imageZoomLevels[len(test.imageZoomLevels)-1]
+		}
+		for i, level := range levels {
+			if level.Zoom != i || [2]int{level.Columns, level.Rows} != test.grid[i] {
+				t.Errorf("%s: level %d is %+v, want %v tiles", test.name, i, level, test.grid[i])
+			}
+		}
+		// the most detailed level uses the largest image zoom and level 0 fits one tile
+		if last := levels[len(levels)-1]; last.Scale != maxScale {
+			t.Errorf("%s: most detailed scale %f, want %f", test.name, last.Scale, maxScale)
+		}
+		if first := levels[0]; first.Width > test.tileSize || first.Height > test.tileSize {
+			t.Errorf("%s: level 0 is %dx%d", test.name, first.Width, first.Height)
+		}
+	}
+}
+
+func TestShapeInside(t *testing.T) {
+	tests := []struct {
+		name  string
+		shape Shape
+		area  [4]float64
+		want  bool
+	}{
+		{"circle inside", Shape{Circle: true, Points: [][2]float64{{0, 0}}, Radius: 5}, [4]float64{-10, -10, 10, 10}, true},
+		{"circle overlapping the edge", Shape{Circle: true, Points: [][2]float64{{14, 0}}, Radius: 5}, [4]float64{-10, -10, 10, 10}, true},
+		{"circle outside", Shape{Circle: true, Points: [][2]float64{{20, 0}}, Radius: 5}, [4]float64{-10, -10, 10, 10}, false},
+		{"stroke reaching in", Shape{Points: [][2]float64{{-20, 12}, {20, 12}}, StrokeWidth: 3}, [4]float64{-10, -10, 10, 10}, true},
+		// neither end is inside, the segment between them crosses the area
+		{"segment crossing", Shape{Points: [][2]float64{{-100, 0}, {100, 0}}, StrokeWidth: 1}, [4]float64{-10, -10, 10, 10}, true},
+		{"segment passing by", Shape{Points: [][2]float64{{-100, 50}, {100, 50}}, StrokeWidth: 1}, [4]float64{-10, -10, 10, 10}, false},
+		{"segment ending before", Shape{Points: [][2]float64{{-100, 0}, {-20, 0}}, StrokeWidth: 1}, [4]float64{-10, -10, 10, 10}, false},
+	}
+	for _, test := range tests {
+		if got := test.shape.inside(test.area[0], test.area[1], test.area[2], test.area[3]); got != test.want {
+			t.Errorf("%s: inside = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWriteTiles(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	background := color.RGBA{0, 0, 0, 0xff}
+	metadata, err := WriteTiles(dir, tree, DrawOptions{}, TileOptions{TileSize: 256, Background: background})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written TileMetadata
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written, metadata) {
+		t.Errorf("metadata.json = %+v, want %+v", written, metadata)
+	}
+	if metadata.MinX != -1200 || metadata.MaxX != 2200 || metadata.MaxZoom != 3 || len(metadata.ZoomLevels) != 4 {
+		t.Errorf("metadata = %+v", metadata)
+	}
+
+	// every level has its columns x rows tiles in zoom/x/y.png
+	for _, level := range metadata.ZoomLevels {
+		count := 0
+		for x := 0; x < level.Columns; x++ {
+			entries, err := os.ReadDir(filepath.Join(dir, strconv.Itoa(level.Zoom), strconv.Itoa(x)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			count += len(entries)
+		}
+		if count != level.Columns*level.Rows {
+			t.Errorf("level %d has %d tiles, want %d", level.Zoom, count, level.Columns*level.Rows)
+		}
+	}
+
+	// the keystone at 2000, 0 is at pixel 1227, 138 of the most detailed
+	// level, which is pixel 203, 138 of tile 4, 0
+	file, err := os.Open(filepath.Join(dir, "3", "4", "0.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	tile, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := tile.Bounds(); bounds.Dx() != 256 || bounds.Dy() != 256 {
+		t.Errorf("tile is %v", bounds)
+	}
+	if c := color.RGBAModel.Convert(tile.At(203, 138)); c == background {
+		t.Error("the keystone is not painted")
+	}
+	if c := color.RGBAModel.Convert(tile.At(0, 255)); c != background {
+		t.Errorf("empty pixel painted %v", c)
+	}
+}
@@ -3,20 +3,22 @@ package main
 import "math"
 
 type Tree struct {
-	Tree            string                       `json:"tree"`
-	Classes         []Classes                    `json:"classes"`
-	Groups          map[string]Group             `json:"groups"`
-	Nodes           map[string]Node              `json:"nodes"`
-	ExtraImages     map[string]ExtraImage        `json:"extraImages"`
-	JewelSlots      []int                        `json:"jewelSlots"`
-	MinX            int                          `json:"min_x"`
-	MaxX            int                          `json:"max_x"`
-	MinY            int                          `json:"min_y"`
-	MaxY            int                          `json:"max_y"`
-	Constants       Constants                    `json:"constants"`
-	Sprites         map[string]map[string]Sprite `json:"sprites"`
-	ImageZoomLevels []float64                    `json:"imageZoomLevels"`
-	Points          PassivePoints                `json:"points"`
+	Tree                  string                       `json:"tree"`
+	Game                  Game                         `json:"-"`
+	Classes               []Classes                    `json:"classes"`
+	AlternateAscendancies []Ascendancy                 `json:"alternate_ascendancies,omitempty"`
+	Groups                map[string]Group             `json:"groups"`
+	Nodes                 map[string]Node              `json:"nodes"`
+	ExtraImages           map[string]ExtraImage        `json:"extraImages"`
+	JewelSlots            []int                        `json:"jewelSlots"`
+	MinX                  int                          `json:"min_x"`
+	MaxX                  int                          `json:"max_x"`
+	MinY                  int                          `json:"min_y"`
+	MaxY                  int                          `json:"max_y"`
+	Constants             Constants                    `json:"constants"`
+	Sprites               map[string]map[string]Sprite `json:"sprites"`
+	ImageZoomLevels       []float64                    `json:"imageZoomLevels"`
+	Points                PassivePoints                `json:"points"`
 }
 
 type CompactTree struct {
@@ -35,6 +37,8 @@ type CompactNode struct {
 	IsNotable   bool     `json:"isNotable,omitempty"`
 	IsKeystone  bool     `json:"isKeystone,omitempty"`
 	IsBloodline bool     `json:"isBloodline,omitempty"`
+	IsAttribute bool     `json:"isAttribute,omitempty"`
+	IsWeaponSet bool     `json:"isWeaponSet,omitempty"`
 }
 
 type Classes struct {
@@ -104,17 +108,26 @@ type Node struct {
 	IsKeystone             bool            `json:"isKeystone,omitempty"`
 	IsWormhole             bool            `json:"isWormhole,omitempty"`
 	IsBloodline            bool            `json:"isBloodline,omitempty"`
+	IsAttribute            bool            `json:"isAttribute,omitempty"`
+	IsWeaponSet            bool            `json:"isWeaponSet,omitempty"`
 	InactiveIcon           *string         `json:"inactiveIcon,omitempty"`
 	ActiveIcon             *string         `json:"activeIcon,omitempty"`
 	ActiveEffectImage      *string         `json:"activeEffectImage,omitempty"`
 	MasteryEffects         []MasteryEffect `json:"masteryEffects,omitempty"`
 	ClassStartIndex        *int            `json:"classStartIndex,omitempty"`
+	// orbit of the arc to a neighbour, only set for path of exile 2 trees
+	ConnectionOrbits map[string]int `json:"-"`
 }
 
 func (n Node) ShouldDraw() bool {
 	return n.ClassStartIndex == nil && !n.IsProxy && !(n.ExpansionJewel != nil && n.ExpansionJewel.Size < 2)
 }
 
+// nodes granting two passive points leave their connections undrawn
+func (n Node) ShouldDrawConnections() bool {
+	return n.GrantedPassivePoints != 2
+}
+
 func (n1 Node) ShouldConnectTo(n2 Node) bool {
 	return !n1.IsMastery && n1.ClassStartIndex == nil && !n2.IsMastery && n2.ClassStartIndex == nil && (!n1.IsWormhole || !n2.IsWormhole)
 }
@@ -166,6 +179,7 @@ type SpriteCoords struct {
 type PassivePoints struct {
 	TotalPoints      int `json:"totalPoints"`
 	AscendancyPoints int `json:"ascendancyPoints"`
+	WeaponSetPoints  int `json:"weaponSetPoints,omitempty"`
 }
 
 func GetOrbitAngle(index int, total int) float64 {
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"treegen/internal/shareurl"
+)
+
+type ValidationIssue struct {
+	Kind    string `json:"kind"`
+	Node    int    `json:"node,omitempty"`
+	Message string `json:"message"`
+}
+
+const (
+	IssueUnknownNode             = "unknown-node"
+	IssueDisconnected            = "disconnected"
+	IssueWrongAscendancy         = "wrong-ascendancy"
+	IssueTooManyPoints           = "too-many-points"
+	IssueTooManyAscendancyPoints = "too-many-ascendancy-points"
+	IssueUnallocatedMastery      = "unallocated-mastery"
+	IssueUnknownMasteryEffect    = "unknown-mastery-effect"
+)
+
+// ValidateBuild checks that a build could have been allocated in game on this
+// tree and returns all problems found, ordered by node
+func (g *Graph) ValidateBuild(build PassiveBuild) ([]ValidationIssue, error) {
+	issues := make([]ValidationIssue, 0)
+	class, err := build.Class(g.Tree)
+	if err != nil {
+		return nil, err
+	}
+	ascendancy, err := build.AscendancyName(g.Tree)
+	if err != nil {
+		return nil, err
+	}
+	alternateAscendancy, err := build.AlternateAscendancyName(g.Tree)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := g.Roots(build.ClassId, ascendancy)
+	if err != nil {
+		return nil, err
+	}
+	if alternateAscendancy != "" {
+		alternateStart, err := g.AscendancyStart(alternateAscendancy)
+		if err == nil {
+			roots = append(roots, alternateStart)
+		}
+	}
+
+	allocated := make(map[int]bool)
+	for _, root := range roots {
+		allocated[root] = true
+	}
+	points, ascendancyPoints, grantedPoints := 0, 0, 0
+	for _, hash := range build.Nodes {
+		node, exists := g.Nodes[hash]
+		if !exists {
+			issues = append(issues, ValidationIssue{Kind: IssueUnknownNode, Node: hash, Message: fmt.Sprintf("node %d does not exist in this tree", hash)})
+			continue
+		}
+		allocated[hash] = true
+		if node.ClassStartIndex != nil || node.IsAscendancyStart {
+			continue
+		}
+		grantedPoints += node.GrantedPassivePoints
+		if node.AscendancyName == nil {
+			points++
+			continue
+		}
+		if *node.AscendancyName != ascendancy && *node.AscendancyName != alternateAscendancy {
+			issues = append(issues, ValidationIssue{Kind: IssueWrongAscendancy, Node: hash, Message: fmt.Sprintf("%s belongs to %s, not to %s", g.NodeName(hash), *node.AscendancyName, ascendancyOrNone(ascendancy))})
+		}
+		if !node.IsBloodline {
+			ascendancyPoints++
+		}
+	}
+
+	connected := g.connectedNodes(roots, allocated)
+	for _, hash := range build.Nodes {
+		if allocated[hash] && !connected[hash] {
+			issues = append(issues, ValidationIssue{Kind: IssueDisconnected, Node: hash, Message: fmt.Sprintf("%s is not connected to the %s start", g.NodeName(hash), class.Name)})
+		}
+	}
+
+	for hash, effect := range build.MasteryEffects {
+		node, exists := g.Nodes[hash]
+		if !exists || !allocated[hash] {
+			issues = append(issues, ValidationIssue{Kind: IssueUnallocatedMastery, Node: hash, Message: fmt.Sprintf("mastery effect %d chosen on unallocated node %d", effect, hash)})
+			continue
+		}
+		found := false
+		for _, masteryEffect := range node.MasteryEffects {
+			if masteryEffect.Effect == effect {
+				found = true
+			}
+		}
+		if !node.IsMastery || !found {
+			issues = append(issues, ValidationIssue{Kind: IssueUnknownMasteryEffect, Node: hash, Message: fmt.Sprintf("%s has no mastery effect %d", g.NodeName(hash), effect)})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Node < issues[j].Node
+	})
+	if totalPoints := g.Tree.Points.TotalPoints + grantedPoints; points > totalPoints {
+		issues = append(issues, ValidationIssue{Kind: IssueTooManyPoints, Message: fmt.Sprintf("%d passive points allocated, only %d available", points, totalPoints)})
+	}
+	if ascendancyPoints > g.Tree.Points.AscendancyPoints {
+		issues = append(issues, ValidationIssue{Kind: IssueTooManyAscendancyPoints, Message: fmt.Sprintf("%d ascendancy points allocated, only %d available", ascendancyPoints, g.Tree.Points.AscendancyPoints)})
+	}
+	return issues, nil
+}
+
+func ascendancyOrNone(ascendancy string) string {
+	if ascendancy == "" {
+		return "no ascendancy"
+	}
+	return ascendancy
+}
+
+// returns the allocated nodes reachable from the roots through allocated nodes,
+// including masteries whose group has a connected node
+func (g *Graph) connectedNodes(roots []int, allocated map[int]bool) map[int]bool {
+	connected := make(map[int]bool)
+	queue := make([]int, 0, len(roots))
+	for _, root := range roots {
+		connected[root] = true
+		queue = append(queue, root)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, neighbour := range g.adjacency[current] {
+			if !allocated[neighbour] || connected[neighbour] || g.Nodes[neighbour].ClassStartIndex != nil {
+				continue
+			}
+			connected[neighbour] = true
+			queue = append(queue, neighbour)
+		}
+	}
+	for hash := range allocated {
+		if !g.Nodes[hash].IsMastery {
+			continue
+		}
+		group := g.Tree.Groups[strconv.Itoa(g.Nodes[hash].Group)]
+		for _, nodeid := range group.Nodes {
+			other, err := strconv.Atoi(nodeid)
+			if err == nil && other != hash && connected[other] {
+				connected[hash] = true
+				break
+			}
+		}
+	}
+	return connected
+}
+
+// parses mastery choices given as node:effect pairs
+func ParseMasteryEffects(s string) (map[int]int, error) {
+	effects := make(map[int]int)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		node, effect, found := strings.Cut(part, ":")
+		nodeHash, err := strconv.Atoi(node)
+		if err != nil || !found {
+			return nil, fmt.Errorf("invalid mastery choice %q, expected node:effect", part)
+		}
+		effectId, err := strconv.Atoi(effect)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mastery choice %q, expected node:effect", part)
+		}
+		effects[nodeHash] = effectId
+	}
+	return effects, nil
+}
+
+func RunValidate(args []string) error {
+	fs, config := NewFlagSet("validate")
+	className := fs.String("class", "", "class of the character")
+	ascendancy := fs.String("ascendancy", "", "ascendancy of the character")
+	nodes := fs.String("nodes", "", "comma separated list of allocated node hashes")
+	masteries := fs.String("masteries", "", "comma separated list of chosen mastery effects as node:effect")
+	url := fs.String("url", "", "passive tree share url to validate instead of -class, -ascendancy, -nodes and -masteries")
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	file, err := SelectTreeFile(cfg)
+	if err != nil {
+		return err
+	}
+	tree, err := LoadTree(file.Path)
+	if err != nil {
+		return err
+	}
+	graph := NewGraph(tree)
+
+	var build PassiveBuild
+	if *url != "" {
+		build.Build, err = shareurl.Decode(*url)
+		if err != nil {
+			return err
+		}
+	} else {
+		allocated, err := ParseNodeList(*nodes)
+		if err != nil {
+			return err
+		}
+		build, err = NewPassiveBuild(tree, *className, *ascendancy, allocated)
+		if err != nil {
+			return err
+		}
+		build.MasteryEffects, err = ParseMasteryEffects(*masteries)
+		if err != nil {
+			return err
+		}
+	}
+
+	issues, err := graph.ValidateBuild(build)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s: %s\n", issue.Kind, issue.Message)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("allocation is invalid for %s %s: %d issues", file.Kind.Name, file.Version, len(issues))
+	}
+	fmt.Printf("Allocation is valid for %s %s\n", file.Kind.Name, file.Version)
+	return nil
+}
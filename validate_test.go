@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"treegen/internal/shareurl"
+)
+
+func TestValidateBuild(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		build  PassiveBuild
+		issues []string
+	}{
+		{"valid", PassiveBuild{shareurl.Build{ClassId: 1, AscendancyId: 1, Nodes: []int{101, 102, 110, 201}, MasteryEffects: map[int]int{110: 48385}}}, []string{}},
+		{"unknown node", PassiveBuild{shareurl.Build{ClassId: 1, Nodes: []int{101, 999}}}, []string{"unknown-node 999"}},
+		{"disconnected", PassiveBuild{shareurl.Build{ClassId: 1, Nodes: []int{101, 103}}}, []string{"disconnected 103"}},
+		{"isolated mastery", PassiveBuild{shareurl.Build{ClassId: 1, Nodes: []int{110}, MasteryEffects: map[int]int{110: 48385}}}, []string{"disconnected 110"}},
+		{"wrong ascendancy", PassiveBuild{shareurl.Build{ClassId: 1, AscendancyId: 2, Nodes: []int{201}}}, []string{"wrong-ascendancy 201", "disconnected 201"}},
+		{"unallocated mastery", PassiveBuild{shareurl.Build{ClassId: 1, Nodes: []int{101}, MasteryEffects: map[int]int{110: 48385}}}, []string{"unallocated-mastery 110"}},
+		{"unknown mastery effect", PassiveBuild{shareurl.Build{ClassId: 1, Nodes: []int{101, 110}, MasteryEffects: map[int]int{110: 1}}}, []string{"unknown-mastery-effect 110"}},
+		{"effect on a passive", PassiveBuild{shareurl.Build{ClassId: 1, Nodes: []int{101}, MasteryEffects: map[int]int{101: 48385}}}, []string{"unknown-mastery-effect 101"}},
+	}
+	graph := NewGraph(tree)
+	for _, test := range tests {
+		issues, err := graph.ValidateBuild(test.build)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		got := make([]string, len(issues))
+		for i, issue := range issues {
+			got[i] = fmt.Sprintf("%s %d", issue.Kind, issue.Node)
+		}
+		if !slices.Equal(got, test.issues) {
+			t.Errorf("%s: got issues %v, want %v", test.name, got, test.issues)
+		}
+	}
+
+	_, err = graph.ValidateBuild(PassiveBuild{shareurl.Build{ClassId: 7}})
+	if err == nil {
+		t.Error("ValidateBuild accepted an unknown class")
+	}
+}
+
+func TestValidateBuildPoints(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Points = PassivePoints{TotalPoints: 2, AscendancyPoints: 0}
+	issues, err := NewGraph(tree).ValidateBuild(PassiveBuild{shareurl.Build{ClassId: 1, AscendancyId: 1, Nodes: []int{101, 102, 103, 201}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make([]string, len(issues))
+	for i, issue := range issues {
+		kinds[i] = issue.Kind
+	}
+	want := []string{IssueTooManyPoints, IssueTooManyAscendancyPoints}
+	if !slices.Equal(kinds, want) {
+		t.Errorf("got issues %v, want %v", kinds, want)
+	}
+}
+
+func TestParseMasteryEffects(t *testing.T) {
+	effects, err := ParseMasteryEffects(" 110:48385, 120:4000,")
+	if err != nil || !maps.Equal(effects, map[int]int{110: 48385, 120: 4000}) {
+		t.Errorf("ParseMasteryEffects = %v, %v", effects, err)
+	}
+	for _, s := range []string{"110", "110:", "a:1", "110:b"} {
+		if _, err := ParseMasteryEffects(s); err == nil {
+			t.Errorf("ParseMasteryEffects(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var viewerTemplate = template.Must(template.New("viewer").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+	html, body { margin: 0; height: 100%; background: #0b0b0b; color: #e0d8c8; font-family: sans-serif; overflow: hidden; }
+	#toolbar { position: fixed; top: 8px; left: 8px; z-index: 2; display: flex; gap: 8px; align-items: center; }
+	#toolbar input { width: 260px; padding: 4px 8px; background: #1b1b1b; color: inherit; border: 1px solid #555; }
+	#viewer { width: 100%; height: 100%; cursor: grab; }
+	#viewer svg { width: 100%; height: 100%; }
+	#tooltip { position: fixed; z-index: 3; display: none; max-width: 360px; padding: 6px 10px; background: rgba(20, 18, 14, 0.95); border: 1px solid #7a6e62; pointer-events: none; }
+	#tooltip .name { color: #ffd700; font-weight: bold; }
+	#tooltip .stat { color: #8888ff; }
+	circle.search-match { stroke: #ff00ff !important; stroke-width: 10 !important; }
+</style>
+</head>
+<body>
+<div id="toolbar">
+	<strong>{{.Title}}</strong>
+	<input id="search" type="search" placeholder="Search nodes" autocomplete="off">
+	<span id="matches"></span>
+</div>
+<div id="viewer">{{.Svg}}</div>
+<div id="tooltip"></div>
+<script type="application/json" id="tree-data">{{.Data}}</script>
+<script>
+(function () {
+	var data = JSON.parse(document.getElementById("tree-data").textContent);
+	var viewer = document.getElementById("viewer");
+	var svg = viewer.querySelector("svg");
+	var tooltip = document.getElementById("tooltip");
+	var box = svg.viewBox.baseVal;
+	var view = { x: box.x, y: box.y, w: box.width, h: box.height };
+
+	function update() {
+		svg.setAttribute("viewBox", view.x + " " + view.y + " " + view.w + " " + view.h);
+	}
+
+	function toTree(event) {
+		var rect = svg.getBoundingClientRect();
+		var scale = Math.max(view.w / rect.width, view.h / rect.height);
+		return {
+			x: view.x + view.w / 2 + (event.clientX - rect.left - rect.width / 2) * scale,
+			y: view.y + view.h / 2 + (event.clientY - rect.top - rect.height / 2) * scale,
+			scale: scale
+		};
+	}
+
+	viewer.addEventListener("wheel", function (event) {
+		event.preventDefault();
+		var point = toTree(event);
+		var factor = event.deltaY < 0 ? 0.8 : 1.25;
+		view.x = point.x - (point.x - view.x) * factor;
+		view.y = point.y - (point.y - view.y) * factor;
+		view.w *= factor;
+		view.h *= factor;
+		update();
+	}, { passive: false });
+
+	var drag = null;
+	viewer.addEventListener("mousedown", function (event) {
+		drag = { x: event.clientX, y: event.clientY, scale: toTree(event).scale };
+		viewer.style.cursor = "grabbing";
+	});
+	window.addEventListener("mouseup", function () {
+		drag = null;
+		viewer.style.cursor = "";
+	});
+	window.addEventListener("mousemove", function (event) {
+		if (drag) {
+			view.x -= (event.clientX - drag.x) * drag.scale;
+			view.y -= (event.clientY - drag.y) * drag.scale;
+			drag.x = event.clientX;
+			drag.y = event.clientY;
+			update();
+		}
+		var node = event.target.id && event.target.id.indexOf("n-") === 0 ? data.nodes[event.target.id.slice(2)] : null;
+		if (!node || drag) {
+			tooltip.style.display = "none";
+			return;
+		}
+		tooltip.textContent = "";
+		var name = document.createElement("div");
+		name.className = "name";
+		name.textContent = node.name || event.target.id.slice(2);
+		tooltip.appendChild(name);
+		(node.stats || []).forEach(function (stat) {
+			var line = document.createElement("div");
+			line.className = "stat";
+			line.textContent = stat;
+			tooltip.appendChild(line);
+		});
+		tooltip.style.left = (event.clientX + 16) + "px";
+		tooltip.style.top = (event.clientY + 16) + "px";
+		tooltip.style.display = "block";
+	});
+
+	document.getElementById("search").addEventListener("input", function (event) {
+		var query = event.target.value.trim().toLowerCase();
+		var count = 0;
+		svg.querySelectorAll("circle.search-match").forEach(function (circle) {
+			circle.classList.remove("search-match");
+		});
+		if (query.length >= 2) {
+			Object.keys(data.nodes).forEach(function (id) {
+				var node = data.nodes[id];
+				var text = [node.name || ""].concat(node.stats || []).join("\n").toLowerCase();
+				var circle = document.getElementById("n-" + id);
+				if (circle && text.indexOf(query) !== -1) {
+					circle.classList.add("search-match");
+					count++;
+				}
+			});
+		}
+		document.getElementById("matches").textContent = query.length >= 2 ? count + " matches" : "";
+	});
+})();
+</script>
+</body>
+</html>
+`))
+
+type viewerPage struct {
+	Title string
+	Svg   template.HTML
+	Data  template.JS
+}
+
+// WriteHtml writes a single page containing the svg, the compact tree and a
+// script for panning, zooming, searching and tooltips, so it works offline
+func WriteHtml(w io.Writer, title string, tree Tree, compactTree CompactTree, options DrawOptions) error {
+	if options.Style == "" && options.StyleLink == "" {
+		options.Style = DefaultStyle
+	}
+	var svgBuffer bytes.Buffer
+	WriteSvg(&svgBuffer, tree, options)
+	// the svg is embedded into the html, without its xml declaration
+	svgData := svgBuffer.Bytes()
+	if start := bytes.Index(svgData, []byte("<svg")); start > 0 {
+		svgData = svgData[start:]
+	}
+
+	var jsonBuffer bytes.Buffer
+	err := WriteCompactJson(&jsonBuffer, compactTree)
+	if err != nil {
+		return err
+	}
+	return viewerTemplate.Execute(w, viewerPage{
+		Title: title,
+		Svg:   template.HTML(svgData),
+		Data:  template.JS(bytes.TrimSpace(jsonBuffer.Bytes())),
+	})
+}
+
+func SaveHtml(file TreeFile, outFileName string, options DrawOptions) error {
+	tree, err := LoadTree(file.Path)
+	if err != nil {
+		return err
+	}
+	compactTree, err := LoadCompactTree(file.Path)
+	if err != nil {
+		return err
+	}
+	outFile, err := os.Create(outFileName)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	return WriteHtml(outFile, fmt.Sprintf("%s %s", file.Kind.Title, file.Version), tree, compactTree, options)
+}
+
+func (c Config) HtmlPath(file TreeFile) string {
+	return filepath.Join(c.OutputDir, "html", file.Kind.Name, file.Version+".html")
+}
+
+func RunHtml(args []string) error {
+	fs, config := NewFlagSet("html")
+	drawOptions := AddDrawFlags(fs)
+	fs.Parse(args)
+	err := CheckNoArgs(fs)
+	if err != nil {
+		return err
+	}
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	options, err := drawOptions()
+	if err != nil {
+		return err
+	}
+	files, err := FindTreeFiles(cfg)
+	if err != nil {
+		return err
+	}
+	err = MakeOutputDirs(cfg, "html")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Printf("Generating HTML viewer for %s %s\n", file.Kind.Name, file.Version)
+		fileOptions, err := options.ForFile(file)
+		if err != nil {
+			return err
+		}
+		err = SaveHtml(file, cfg.HtmlPath(file), fileOptions)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
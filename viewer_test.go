@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteHtml(t *testing.T) {
+	tree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compactTree, err := LoadCompactTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	options := DrawOptions{Allocation: &Allocation{Nodes: []int{101, 102}}}
+	if err := WriteHtml(&buffer, "Passive tree 3.25 & more", tree, compactTree, options); err != nil {
+		t.Fatal(err)
+	}
+	page := buffer.String()
+	if !strings.Contains(page, "<title>Passive tree 3.25 &amp; more</title>") {
+		t.Error("the title is missing or not escaped")
+	}
+	// the svg is inlined without its xml declaration and with the default style
+	if !strings.Contains(page, `<div id="viewer"><svg`) || strings.Contains(page, "<?xml") {
+		t.Error("the svg is not inlined into the viewer")
+	}
+	if !strings.Contains(page, `<style type="text/css">`) {
+		t.Error("the svg has no style")
+	}
+
+	match := regexp.MustCompile(`(?s)<script type="application/json" id="tree-data">(.*?)</script>`).FindStringSubmatch(page)
+	if match == nil {
+		t.Fatal("no tree data block")
+	}
+	var data CompactTree
+	if err := json.Unmarshal([]byte(match[1]), &data); err != nil {
+		t.Fatalf("the tree data is not json: %v", err)
+	}
+	if len(data.Nodes) != len(compactTree.Nodes) || len(data.Groups) != len(compactTree.Groups) {
+		t.Errorf("the tree data has %d nodes and %d groups, want %d and %d", len(data.Nodes), len(data.Groups), len(compactTree.Nodes), len(compactTree.Groups))
+	}
+
+	// the allocation given with -nodes is highlighted before any script runs
+	for _, id := range []string{"n-101", "n-102", "c-101-102"} {
+		if classes := svgClasses(svgElement(page, id)); !slices.Contains(classes, "allocated") {
+			t.Errorf("%s is not allocated: %v", id, classes)
+		}
+	}
+	if classes := svgClasses(svgElement(page, "n-103")); slices.Contains(classes, "allocated") {
+		t.Errorf("n-103 is allocated: %v", classes)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strconv"
+)
+
+type DiffOverlay struct {
+	OldTree Tree
+	Diff    TreeDiff
+}
+
+func NewDiffOverlay(oldTree Tree, newTree Tree) *DiffOverlay {
+	return &DiffOverlay{
+		OldTree: oldTree,
+		Diff:    DiffTrees(oldTree, newTree),
+	}
+}
+
+// identifies a connection independent of its direction
+func ConnectionKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+func TreeConnections(tree Tree) map[[2]int]bool {
+	connections := make(map[[2]int]bool)
+	for nodeid, node := range tree.Nodes {
+		hash, err := strconv.Atoi(nodeid)
+		if err != nil {
+			continue
+		}
+		for _, neighbourId := range node.Out {
+			neighbour, err := strconv.Atoi(neighbourId)
+			if err != nil {
+				continue
+			}
+			connections[ConnectionKey(hash, neighbour)] = true
+		}
+	}
+	return connections
+}
+
+// marks added, changed and moved nodes and connections of the new tree and
+// prepares a drawer for the removed ones at their old coordinates
+func (d *TreeDrawer) applyDiff(overlay DiffOverlay) {
+	previous := &TreeDrawer{
+		s:                 d.s,
+		Tree:              overlay.OldTree,
+		nodeClasses:       make(map[int][]string),
+		connectionClasses: make(map[[2]int][]string),
+	}
+	d.previous = previous
+
+	for _, node := range overlay.Diff.Added {
+		d.nodeClasses[node.Hash] = append(d.nodeClasses[node.Hash], "added")
+	}
+	for _, node := range overlay.Diff.Removed {
+		previous.nodeClasses[node.Hash] = append(previous.nodeClasses[node.Hash], "removed")
+	}
+	changed := make(map[int]bool)
+	for _, change := range append(append([]NodeChange{}, overlay.Diff.Changed...), overlay.Diff.Renamed...) {
+		if !changed[change.Hash] {
+			changed[change.Hash] = true
+			d.nodeClasses[change.Hash] = append(d.nodeClasses[change.Hash], "changed")
+		}
+	}
+	moved := make(map[int]bool)
+	for _, change := range overlay.Diff.Moved {
+		moved[change.Hash] = true
+		d.nodeClasses[change.Hash] = append(d.nodeClasses[change.Hash], "moved")
+	}
+
+	oldConnections := TreeConnections(overlay.OldTree)
+	newConnections := TreeConnections(d.Tree)
+	for key := range newConnections {
+		if !oldConnections[key] {
+			d.connectionClasses[key] = append(d.connectionClasses[key], "added")
+		} else if moved[key[0]] || moved[key[1]] {
+			d.connectionClasses[key] = append(d.connectionClasses[key], "moved")
+		}
+	}
+	for key := range oldConnections {
+		if !newConnections[key] {
+			previous.connectionClasses[key] = append(previous.connectionClasses[key], "removed")
+		}
+	}
+}
+
+func (d *TreeDrawer) DrawRemovedConnections() {
+	for _, nodeid := range SortedNodeIds(d.Tree) {
+		node := d.Tree.Nodes[nodeid]
+		if !node.ShouldDrawConnections() {
+			continue
+		}
+		for _, neighbourId := range node.Out {
+			neighbour := d.Tree.Nodes[neighbourId]
+			if len(d.connectionClasses[ConnectionKey(node.Skill, neighbour.Skill)]) > 0 {
+				d.DrawConnection(node, neighbour)
+			}
+		}
+	}
+}
+
+func (d *TreeDrawer) DrawRemovedNodes() {
+	for _, nodeid := range SortedNodeIds(d.Tree) {
+		node := d.Tree.Nodes[nodeid]
+		if len(d.nodeClasses[node.Skill]) > 0 {
+			d.DrawNode(node)
+		}
+	}
+}
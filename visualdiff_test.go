@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffOverlay(t *testing.T) {
+	oldTree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTree, err := LoadTree("testdata/skilltree/3.25.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 103 is removed, 600 is added after the keystone, 101 is changed and 102 moved
+	fresh := "Fresh"
+	newTree.Nodes["600"] = Node{Skill: 600, Name: &fresh, Group: 6, In: []string{"104"}}
+	delete(newTree.Nodes, "103")
+	changed := newTree.Nodes["101"]
+	changed.Stats = []string{"+12 to Intelligence"}
+	newTree.Nodes["101"] = changed
+	moved := newTree.Nodes["102"]
+	moved.OrbitIndex, moved.Out = 5, []string{}
+	newTree.Nodes["102"] = moved
+	keystone := newTree.Nodes["104"]
+	keystone.In, keystone.Out = []string{}, []string{"600"}
+	newTree.Nodes["104"] = keystone
+
+	svg := renderSvg(t, newTree, DrawOptions{Diff: NewDiffOverlay(oldTree, newTree)})
+	tests := []struct {
+		id      string
+		classes []string
+	}{
+		{"n-600", []string{"added"}},
+		{"n-103", []string{"removed"}},
+		{"n-101", []string{"changed"}},
+		{"n-102", []string{"moved"}},
+		{"n-104", []string{"keystone"}},
+		{"c-104-600", []string{"added"}},
+		{"c-102-103", []string{"removed"}},
+		{"c-103-104", []string{"removed"}},
+		{"c-101-102", []string{"moved"}},
+		{"c-200-201", []string{"ascendancy"}},
+	}
+	for _, test := range tests {
+		element := svgElement(svg, test.id)
+		if element == "" {
+			t.Errorf("%s is not drawn", test.id)
+			continue
+		}
+		if classes := svgClasses(element); !slices.Equal(classes, test.classes) {
+			t.Errorf("%s has classes %v, want %v", test.id, classes, test.classes)
+		}
+	}
+
+	// removed connections skip the same nodes as the connections of the new tree
+	removed := oldTree.Nodes["103"]
+	removed.GrantedPassivePoints = 2
+	oldTree.Nodes["103"] = removed
+	svg = renderSvg(t, newTree, DrawOptions{Diff: NewDiffOverlay(oldTree, newTree)})
+	if element := svgElement(svg, "c-103-104"); element != "" {
+		t.Errorf("drew the removed connection of a node granting passive points: %s", element)
+	}
+	if element := svgElement(svg, "c-102-103"); element == "" {
+		t.Error("the removed connection to the node is missing")
+	}
+}